@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
@@ -28,6 +29,72 @@ func contextsCompletionFunc(
 	return comps, cobra.ShellCompDirectiveNoFileComp
 }
 
+// contextListCompletionFunc completes the last context of a comma separated list of kubeconfig contexts.
+func contextListCompletionFunc(
+	kubeconfigArgs *genericclioptions.ConfigFlags,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	comps, directive := contextsCompletionFunc(kubeconfigArgs, toComplete)
+	for i := range comps {
+		comps[i] = prefix + comps[i]
+	}
+
+	return comps, directive
+}
+
+// kindsCompletionFunc completes the api resources served by the cluster as resource.group, the group is omitted
+// for the core group.
+func kindsCompletionFunc(
+	kubeconfigArgs *genericclioptions.ConfigFlags,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	client, err := kubeconfigArgs.ToDiscoveryClient()
+	if err != nil {
+		return completionError(err)
+	}
+
+	lists, err := client.ServerPreferredResources()
+	if err != nil && len(lists) == 0 {
+		return completionError(err)
+	}
+
+	// completes the last kind of a comma separated list
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	var comps []string
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+
+			name := resource.Name
+			if gv.Group != "" {
+				name += "." + gv.Group
+			}
+
+			if strings.HasPrefix(name, toComplete) {
+				comps = append(comps, prefix+name)
+			}
+		}
+	}
+
+	return comps, cobra.ShellCompDirectiveNoFileComp
+}
+
 func completionError(err error) ([]string, cobra.ShellCompDirective) {
 	cobra.CompError(err.Error())
 	return nil, cobra.ShellCompDirectiveError
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	gitopszombiesv1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+const (
+	discoveryTimeout = 10 * time.Second
+	schemaURL        = "https://raw.githubusercontent.com/raffis/gitops-zombies/main/pkg/apis/gitopszombies/v2/config.schema.json"
+)
+
+const starterConfig = `---
+# yaml-language-server: $schema={{ .SchemaURL }}
+# gitops-zombies config, all fields besides apiVersion and kind of resource rules are regular expressions.
+apiVersion: {{ .APIVersion }}
+kind: Config
+
+# Report resources which are considered dynamic as well (the builtin blacklist below and kubernetes bootstrap resources).
+# includeAll: false
+
+# Label selector used while listing all apis.
+# selector: app.kubernetes.io/managed-by!=kops
+
+# Label selectors used while listing specific apis, an empty version matches all versions.
+# selectors:
+# - group: cilium.io
+#   resource: ciliumidentities
+#   selector: io.cilium.k8s.policy.cluster!=default
+
+# Resources which are considered dynamic on top of the builtin ones, entries match all versions of a resource.
+# The builtin blacklist is applied unless includeAll is set:
+{{- range .Blacklist }}
+#   {{ .Resource }}{{ if .Group }}.{{ .Group }}{{ end }}
+{{- end }}
+# blacklist:
+# - group: example.com
+#   resource: sessions
+
+# Ignore objects well-known controllers create without owner references, one of: {{ .IgnorePresets }}.
+# ignorePresets:
+# - kubernetes-defaults
+
+# Curated blacklists of common ecosystems, one of: {{ .BlacklistPresets }}.
+# blacklistPresets:
+# - cilium
+# - velero
+
+# Resources removed from the builtin blacklist and the presets.
+# excludeBlacklist:
+# - resource: persistentvolumeclaims
+
+# Restrict zombie detection to namespaces (glob or regexp), cluster scoped resources are skipped unless
+# includeClusterScoped is set.
+# includeNamespaces:
+# - apps-*
+# excludeNamespaces:
+# - kube-system
+# includeClusterScoped: true
+
+# Restrict zombie detection to kinds (kind, kind.group or resource.version.group), kinds considered dynamic
+# resources are included if they are requested explicitly.
+# kinds:
+# - clusterrolebindings.rbac.authorization.k8s.io
+# - persistentvolumeclaims
+# excludeKinds:
+# - secrets
+
+# Only report resources matching any of these rules.
+# includeResources:
+# - apiVersion: apps/v1
+#   kind: Deployment
+
+# Never report resources matching any of these rules.
+# Expressions are evaluated using CEL, object.age(), object.ownerKinds(), object.hasLabel(key) and
+# object.hasAnnotation(key) are available besides the standard library.
+# excludeResources:
+# - apiVersion: batch/v1
+#   kind: Job
+#   expression: object.age() > duration('168h')
+# - apiVersion: v1
+#   kind: ServiceAccount
+#   name: default
+# - apiVersion: velero.io/v1
+#   kind: Backup
+#   namespace: velero
+#   name: velero-capi-backup-.*
+#   cluster: management
+
+# Report zombies matching a rule with its severity (info, warning, critical) instead of the builtin one.
+# severities:
+# - apiVersion: v1
+#   kind: ConfigMap
+#   namespace: legacy-.*
+#   severity: critical
+
+# Credential plugins which kubeconfigs of remote clusters may execute with your local credentials, none by default.
+# aws, aws-iam-authenticator, gke-gcloud-auth-plugin and kubelogin are restricted to issuing tokens.
+# allowedExecCommands:
+# - aws
+# - /usr/local/bin/pinniped
+
+# Request tokens of the ServiceAccounts configured by generic workload identity kubeConfigs (TokenRequest, a write on
+# the flux cluster) to scan their clusters.
+# requestServiceAccountTokens: true
+
+# Scan the clusters of all Cluster API clusters by their <name>-kubeconfig secret, including clusters no
+# Kustomization or HelmRelease targets.
+# discoverClusterAPI: true
+
+# Scan each cluster once per ServiceAccount flux impersonates (spec.serviceAccountName) instead of your own identity.
+# impersonateServiceAccounts: true
+
+# Clusters excluded from zombie detection. The flux cluster is named self, remote clusters are named by their kubeConfig
+# secret or config map (namespace/name[/key]) unless it is annotated with gitops-zombies.io/cluster-name.
+# excludeClusters:
+# - staging
+
+# Overrides for clusters matching the name, lists are appended to the global ones while set values replace them.
+# clusters:
+# - name: management
+#   includeAll: true
+#   excludeNamespaces:
+#   - capi-.*
+
+# Report releases installed by the helm cli which are not managed by a HelmRelease once by their storage secret
+# instead of each of their resources.
+# detectHelmReleases: true
+
+# Ignore resources younger than the given age.
+# minAge: 24h
+
+# Sort zombies (age), implies noStream.
+# sortBy: age
+
+# Display discovered resources at the end instead of live.
+# noStream: false
+
+# Add the findings (severity, last modification, references, ...) as gitops-zombies.io/* annotations to the zombies
+# printed by structured output formats (-o yaml, -o json, ...).
+# annotate: true
+
+# Exit with an exit code > 0 if zombies are detected.
+# fail: false
+
+# Exit with an exit code > 0 if zombies of the given or a higher severity (info, warning, critical) are detected
+# or if more zombies of a severity than its threshold are detected.
+# failOn: critical
+# failThreshold:
+#   warning: 10
+`
+
+func newConfigCmd(cfgFiles *[]string, kubeconfigArgs *genericclioptions.ConfigFlags) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Validate or initialize the gitops-zombies config",
+	}
+
+	configCmd.AddCommand(newConfigValidateCmd(cfgFiles, kubeconfigArgs), newConfigInitCmd(cfgFiles), newConfigSchemaCmd())
+	return configCmd
+}
+
+func newConfigValidateCmd(cfgFiles *[]string, kubeconfigArgs *genericclioptions.ConfigFlags) *cobra.Command {
+	var offline bool
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config",
+		Long: `Decodes the config strictly, compiles all regular expressions and validates label selectors.
+If the cluster is reachable the kinds and resources referenced by the config are validated against the served apis.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			setStatus(cmd, statusFail)
+
+			var lists []*metav1.APIResourceList
+			if !offline {
+				var err error
+				lists, err = discoverAPIs(cmd.Context(), kubeconfigArgs)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "skip api validation, cluster is not reachable: %s\n", err)
+					offline = true
+				}
+			}
+
+			var invalid int
+			for _, cfgFile := range *cfgFiles {
+				data, err := os.ReadFile(cfgFile)
+				if err != nil {
+					return err
+				}
+
+				conf, err := decodeConfig(data)
+				if err != nil {
+					return fmt.Errorf("failed to decode config %s: %w", cfgFile, err)
+				}
+
+				errs := validateConfig(conf)
+				if !offline {
+					errs = append(errs, conf.ValidateAPIs(lists)...)
+				}
+
+				if len(errs) > 0 {
+					invalid++
+					for _, err := range errs {
+						fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", cfgFile, err)
+					}
+
+					continue
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "config %s is valid\n", cfgFile)
+			}
+
+			if invalid > 0 {
+				return fmt.Errorf("%d invalid config(s) found", invalid)
+			}
+
+			setStatus(cmd, statusOK)
+			return nil
+		},
+	}
+
+	validateCmd.Flags().BoolVarP(&offline, "offline", "", false, "Do not validate kinds and resources against the cluster")
+	return validateCmd
+}
+
+func newConfigInitCmd(cfgFiles *[]string) *cobra.Command {
+	var force bool
+
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a commented starter config",
+		Long:  `Writes a commented starter config including the builtin blacklist to the config path. Use --config=- to print it instead.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			setStatus(cmd, statusFail)
+
+			if len(*cfgFiles) != 1 {
+				return errors.New("config init requires exactly one config path")
+			}
+
+			cfgFile := (*cfgFiles)[0]
+			if cfgFile == "-" {
+				if err := writeStarterConfig(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+
+				setStatus(cmd, statusOK)
+				return nil
+			}
+
+			flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if !force {
+				flags |= os.O_EXCL
+			}
+
+			f, err := os.OpenFile(cfgFile, flags, 0o644)
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("config %s already exists, use --force to overwrite it", cfgFile)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := writeStarterConfig(f); err != nil {
+				_ = f.Close()
+				return err
+			}
+
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "config written to %s\n", cfgFile)
+			setStatus(cmd, statusOK)
+			return nil
+		},
+	}
+
+	initCmd.Flags().BoolVarP(&force, "force", "", false, "Overwrite an existing config")
+	return initCmd
+}
+
+func newConfigSchemaCmd() *cobra.Command {
+	apiVersion := v2.SchemeGroupVersion.String()
+	schemas := map[string][]byte{
+		gitopszombiesv1.SchemeGroupVersion.String(): gitopszombiesv1.JSONSchema,
+		v2.SchemeGroupVersion.String():              v2.JSONSchema,
+	}
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schema of the config",
+		Long:  `Prints the JSON schema of the config which can be used by editors for completion and validation.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			setStatus(cmd, statusFail)
+
+			schema, ok := schemas[apiVersion]
+			if !ok {
+				return fmt.Errorf("unsupported api version %s, expected one of: (%s)",
+					apiVersion, strings.Join(slices.Sorted(maps.Keys(schemas)), ", "))
+			}
+
+			if _, err := cmd.OutOrStdout().Write(schema); err != nil {
+				return err
+			}
+
+			setStatus(cmd, statusOK)
+			return nil
+		},
+	}
+
+	schemaCmd.Flags().StringVarP(&apiVersion, "api-version", "", apiVersion, "Config api version")
+	return schemaCmd
+}
+
+func writeStarterConfig(w io.Writer) error {
+	tmpl, err := template.New("config").Parse(starterConfig)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, map[string]any{
+		"APIVersion":       v2.SchemeGroupVersion.String(),
+		"SchemaURL":        schemaURL,
+		"Blacklist":        v2.DefaultBlacklist(),
+		"BlacklistPresets": strings.Join(v2.BlacklistPresets(), ", "),
+		"IgnorePresets":    strings.Join(v2.IgnorePresets(), ", "),
+	})
+}
+
+// discoverAPIs returns the apis served by the cluster of the current context.
+func discoverAPIs(ctx context.Context, kubeconfigArgs *genericclioptions.ConfigFlags) ([]*metav1.APIResourceList, error) {
+	restConfig, err := kubeconfigArgs.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig.Timeout = discoveryTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	_, lists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil && len(lists) == 0 {
+		return nil, err
+	}
+
+	if err != nil {
+		klog.FromContext(ctx).V(1).Info("failed to discover some apis", "error", err)
+	}
+
+	return lists, nil
+}
+
+func setStatus(cmd *cobra.Command, status int) {
+	root := cmd.Root()
+	if root.Annotations == nil {
+		root.Annotations = make(map[string]string)
+	}
+
+	root.Annotations[statusAnnotation] = strconv.Itoa(status)
+}
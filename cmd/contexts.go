@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	k8sget "k8s.io/kubectl/pkg/cmd/get"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/detector"
+)
+
+// detection is the result of detecting zombies using a kubeconfig context.
+type detection struct {
+	context       string
+	detect        *detector.Detector
+	resourceCount int
+	zombies       map[string][]unstructured.Unstructured
+	err           error
+}
+
+// resolveContexts returns the kubeconfig contexts to scan, all contexts of the kubeconfig if all is set.
+func resolveContexts(kubeconfigArgs *genericclioptions.ConfigFlags, contexts []string, all bool) ([]string, error) {
+	if !all && len(contexts) == 0 {
+		return nil, nil
+	}
+
+	rawConfig, err := kubeconfigArgs.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if all {
+		return slices.Sorted(maps.Keys(rawConfig.Contexts)), nil
+	}
+
+	for _, name := range contexts {
+		if _, ok := rawConfig.Contexts[name]; !ok {
+			return nil, fmt.Errorf("context %q not found in kubeconfig", name)
+		}
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(contexts))), nil
+}
+
+// contextConfigFlags returns the kubeconfig flags using another context.
+// The flags of the cluster and user are dropped as they are defined by the context.
+func contextConfigFlags(base *genericclioptions.ConfigFlags, name string) *genericclioptions.ConfigFlags {
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.CacheDir = base.CacheDir
+	flags.KubeConfig = base.KubeConfig
+	flags.Context = &name
+	flags.Namespace = base.Namespace
+	flags.Impersonate = base.Impersonate
+	flags.ImpersonateUID = base.ImpersonateUID
+	flags.ImpersonateGroup = base.ImpersonateGroup
+	flags.ImpersonateUserExtra = base.ImpersonateUserExtra
+	flags.Timeout = base.Timeout
+	flags.DisableCompression = base.DisableCompression
+	return flags
+}
+
+// detectContext detects the zombies of the flux cluster of a kubeconfig context and its remote clusters.
+// An empty context uses the kubeconfig flags as they are.
+func detectContext(
+	ctx context.Context,
+	conf *v2.Config,
+	kubeconfigArgs *genericclioptions.ConfigFlags,
+	printFlags *k8sget.PrintFlags,
+	name string,
+) detection {
+	result := detection{context: name}
+	if name != "" {
+		kubeconfigArgs = contextConfigFlags(kubeconfigArgs, name)
+	}
+
+	// config fragments are loaded from the flux cluster of each context
+	if conf.ConfigMapSelector != "" {
+		restConfig, err := kubeconfigArgs.ToRESTConfig()
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		fragments, err := loadConfigMapFragments(ctx, restConfig, conf.ConfigMapSelector)
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		conf = conf.DeepCopy()
+		for _, fragment := range fragments {
+			conf.Merge(fragment)
+		}
+
+		if err := validateConfig(conf).ToAggregate(); err != nil {
+			result.err = err
+			return result
+		}
+	}
+
+	result.detect, result.err = detector.New(conf, kubeconfigArgs, printFlags)
+	if result.err != nil {
+		return result
+	}
+
+	if name != "" {
+		result.detect.SetContext(name)
+	}
+
+	result.resourceCount, result.zombies, result.err = result.detect.DetectZombies()
+	return result
+}
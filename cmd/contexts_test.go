@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	k8sget "k8s.io/kubectl/pkg/cmd/get"
+	"k8s.io/utils/ptr"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: eu
+  cluster:
+    server: https://eu.example.com
+- name: us
+  cluster:
+    server: https://us.example.com
+contexts:
+- name: prod-eu
+  context:
+    cluster: eu
+    user: admin
+- name: prod-us
+  context:
+    cluster: us
+    user: admin
+- name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+  context:
+    cluster: eu
+    user: admin
+current-context: prod-eu
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func newTestConfigFlags(t *testing.T) *genericclioptions.ConfigFlags {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NilError(t, os.WriteFile(path, []byte(testKubeConfig), 0o600))
+
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.KubeConfig = &path
+	return flags
+}
+
+func TestResolveContexts(t *testing.T) {
+	tests := []struct {
+		name        string
+		contexts    []string
+		all         bool
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name: "no contexts",
+		},
+		{
+			name:     "all contexts",
+			all:      true,
+			expected: []string{"arn:aws:eks:eu-west-1:123456789012:cluster/prod", "prod-eu", "prod-us"},
+		},
+		{
+			name:     "contexts are sorted and deduplicated",
+			contexts: []string{"prod-us", "prod-eu", "prod-us"},
+			expected: []string{"prod-eu", "prod-us"},
+		},
+		{
+			name:        "unknown context",
+			contexts:    []string{"prod-eu", "staging"},
+			expectedErr: `context "staging" not found in kubeconfig`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contexts, err := resolveContexts(newTestConfigFlags(t), test.contexts, test.all)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, contexts, test.expected)
+		})
+	}
+}
+
+func TestContextConfigFlags(t *testing.T) {
+	base := newTestConfigFlags(t)
+	base.Namespace = ptr.To("apps")
+	base.Impersonate = ptr.To("jane")
+	base.Timeout = ptr.To("10s")
+	base.ClusterName = ptr.To("eu")
+	base.APIServer = ptr.To("https://other.example.com")
+	base.BearerToken = ptr.To("other")
+
+	flags := contextConfigFlags(base, "prod-us")
+	assert.Equal(t, *flags.Context, "prod-us")
+	assert.Equal(t, *flags.KubeConfig, *base.KubeConfig)
+	assert.Equal(t, *flags.Namespace, "apps")
+	assert.Equal(t, *flags.Impersonate, "jane")
+	assert.Equal(t, *flags.Timeout, "10s")
+
+	// the cluster and user are defined by the context
+	restConfig, err := flags.ToRESTConfig()
+	assert.NilError(t, err)
+	assert.Equal(t, restConfig.Host, "https://us.example.com")
+	assert.Equal(t, restConfig.BearerToken, "secret")
+	assert.Equal(t, restConfig.Timeout, 10*time.Second)
+}
+
+func TestZombieList(t *testing.T) {
+	zombie := unstructured.Unstructured{}
+	zombie.SetAPIVersion("v1")
+	zombie.SetKind("ConfigMap")
+	zombie.SetName("debug")
+	zombie.SetNamespace("default")
+	zombie.SetAnnotations(map[string]string{"gitops-zombies.io/context": "prod-eu", "gitops-zombies.io/cluster": "self"})
+
+	tests := []struct {
+		name    string
+		zombies []unstructured.Unstructured
+	}{
+		{
+			name: "no zombies",
+		},
+		{
+			name:    "zombies",
+			zombies: []unstructured.Unstructured{zombie, zombie},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			printFlags := k8sget.NewGetPrintFlags()
+			printFlags.OutputFormat = ptr.To("json")
+			p, err := printFlags.ToPrinter()
+			assert.NilError(t, err)
+
+			var buf bytes.Buffer
+			assert.NilError(t, p.PrintObj(zombieList(test.zombies), &buf))
+
+			var list struct {
+				APIVersion string                       `json:"apiVersion"`
+				Kind       string                       `json:"kind"`
+				Items      []map[string]json.RawMessage `json:"items"`
+			}
+			assert.NilError(t, json.Unmarshal(buf.Bytes(), &list))
+			assert.Equal(t, list.APIVersion, "v1")
+			assert.Equal(t, list.Kind, "List")
+			assert.Equal(t, len(list.Items), len(test.zombies))
+			assert.Assert(t, list.Items != nil, "items must be printed as an empty list")
+		})
+	}
+}
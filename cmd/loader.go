@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	gitopszombiesv1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+const (
+	// envPrefix is the prefix of the environment variables overriding config fields.
+	envPrefix = "GITOPS_ZOMBIES_"
+	// configMapKey is the key of a config fragment within a ConfigMap.
+	configMapKey = "config.yaml"
+)
+
+var configScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(gitopszombiesv1.AddToScheme(configScheme))
+	utilruntime.Must(v2.AddToScheme(configScheme))
+}
+
+// loadConfig loads and merges the configs in order. Lists are concatenated while set values of later configs
+// replace earlier ones. Configs which do not exist are skipped if they are optional.
+func loadConfig(configPaths []string, optional bool) (*v2.Config, error) {
+	conf := &v2.Config{}
+	for _, configPath := range configPaths {
+		_, err := os.Stat(configPath)
+		if err != nil {
+			if os.IsNotExist(err) && optional {
+				klog.V(1).Infof("Can't find config file at %s", configPath)
+				continue
+			}
+			return nil, err
+		}
+
+		json, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, err := decodeConfig(json)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode config %s: %w", configPath, err)
+		}
+
+		if err := validateConfig(cfg).ToAggregate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+		}
+
+		conf.Merge(cfg)
+	}
+
+	return conf, nil
+}
+
+// decodeConfig strictly decodes a config of any supported version and converts it to the latest version.
+func decodeConfig(data []byte) (*v2.Config, error) {
+	codecs := serializer.NewCodecFactory(configScheme, serializer.EnableStrict)
+	obj, gvk, err := codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		return nil, fmt.Errorf("unsupported config %s, expected kind Config of %s or %s",
+			gvk, v2.SchemeGroupVersion, gitopszombiesv1.SchemeGroupVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := obj.(type) {
+	case *v2.Config:
+		return o, nil
+	case *gitopszombiesv1.Config:
+		var cfg v2.Config
+		if err := configScheme.Convert(o, &cfg, nil); err != nil {
+			return nil, err
+		}
+
+		return &cfg, nil
+	default:
+		return nil, fmt.Errorf("unsupported config %s, expected kind Config of %s or %s",
+			gvk, v2.SchemeGroupVersion, gitopszombiesv1.SchemeGroupVersion)
+	}
+}
+
+// validateConfig validates the config including the expressions of its rules.
+func validateConfig(conf *v2.Config) field.ErrorList {
+	errs := conf.Validate()
+	errs = append(errs, collector.CompileExpressions(conf)...)
+	return errs
+}
+
+// applyEnv applies the GITOPS_ZOMBIES_* environment variables named after the config fields (e.g. GITOPS_ZOMBIES_MIN_AGE).
+// Lists are appended to the existing ones, lists of strings are comma separated while all other non scalar
+// fields are decoded from yaml.
+func applyEnv(conf *v2.Config, lookup func(string) (string, bool)) error {
+	v := reflect.ValueOf(conf).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		env := envName(name)
+		value, ok := lookup(env)
+		if !ok {
+			continue
+		}
+
+		parsed, err := parseEnvValue(field.Type, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", env, err)
+		}
+
+		if field.Type.Kind() == reflect.Slice {
+			v.Field(i).Set(reflect.AppendSlice(v.Field(i), parsed))
+			continue
+		}
+
+		v.Field(i).Set(parsed)
+	}
+
+	return nil
+}
+
+// parseEnvValue parses the value of an environment variable into a value of the given config field type.
+func parseEnvValue(t reflect.Type, value string) (reflect.Value, error) {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		// optional scalars like booleans which may be set to false explicitly
+		elem, err := parseEnvValue(t.Elem(), value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		parsed := reflect.New(t.Elem())
+		parsed.Elem().Set(elem)
+		return parsed, nil
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(value).Convert(t), nil
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		return reflect.ValueOf(b).Convert(t), err
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		var list []string
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+
+		return reflect.ValueOf(list).Convert(t), nil
+	}
+
+	// metav1.Duration and structured fields like excludeResources
+	parsed := reflect.New(t)
+	if err := yaml.UnmarshalStrict([]byte(value), parsed.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return parsed.Elem(), nil
+}
+
+// envName converts a json field name into an environment variable name, fluxSSAOwnership becomes
+// GITOPS_ZOMBIES_FLUX_SSA_OWNERSHIP.
+func envName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteRune('_')
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// loadConfigMapFragments reads the config fragments of all ConfigMaps matching the selector from the flux cluster.
+// Fragments may only exclude resources, their rules are scoped to the namespace of the ConfigMap.
+func loadConfigMapFragments(ctx context.Context, restConfig *rest.Config, selector string) ([]*v2.Config, error) {
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config maps: %w", err)
+	}
+
+	var fragments []*v2.Config
+	var errs []error
+	for _, configMap := range list.Items {
+		ref := configMap.Namespace + "/" + configMap.Name
+		data, ok := configMap.Data[configMapKey]
+		if !ok {
+			klog.V(1).Infof("ConfigMap %s has no %s key, skipping it", ref, configMapKey)
+			continue
+		}
+
+		fragment, err := decodeConfig([]byte(data))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to decode config of ConfigMap %s: %w", ref, err))
+			continue
+		}
+
+		if !reflect.DeepEqual(fragment, &v2.Config{TypeMeta: fragment.TypeMeta, ExcludeResources: fragment.ExcludeResources}) {
+			errs = append(errs, fmt.Errorf("config of ConfigMap %s may only contain excludeResources", ref))
+			continue
+		}
+
+		if err := validateConfig(fragment).ToAggregate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid config of ConfigMap %s: %w", ref, err))
+			continue
+		}
+
+		for i := range fragment.ExcludeResources {
+			fragment.ExcludeResources[i].Namespace = regexp.QuoteMeta(configMap.Namespace)
+		}
+
+		fragments = append(fragments, fragment)
+	}
+
+	return fragments, errors.Join(errs...)
+}
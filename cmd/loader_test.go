@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		conf        v2.Config
+		env         map[string]string
+		expected    v2.Config
+		expectedErr string
+	}{
+		{
+			name:     "no environment variables",
+			conf:     v2.Config{Fail: ptr.To(true)},
+			expected: v2.Config{Fail: ptr.To(true)},
+		},
+		{
+			name: "scalars replace existing values",
+			conf: v2.Config{LabelSelector: "app=web", MinAge: metav1.Duration{Duration: time.Hour}},
+			env: map[string]string{
+				"GITOPS_ZOMBIES_SELECTOR": "app=api",
+				"GITOPS_ZOMBIES_MIN_AGE":  "24h",
+				"GITOPS_ZOMBIES_FAIL_ON":  "warning",
+			},
+			expected: v2.Config{
+				FailOn:        v2.SeverityWarning,
+				LabelSelector: "app=api",
+				MinAge:        metav1.Duration{Duration: 24 * time.Hour},
+			},
+		},
+		{
+			name: "booleans may be set to false",
+			conf: v2.Config{Fail: ptr.To(true), Tree: ptr.To(true)},
+			env: map[string]string{
+				"GITOPS_ZOMBIES_FAIL":               "false",
+				"GITOPS_ZOMBIES_FLUX_SSA_OWNERSHIP": "true",
+			},
+			expected: v2.Config{Fail: ptr.To(false), FluxSSAOwnership: ptr.To(true), Tree: ptr.To(true)},
+		},
+		{
+			name: "lists are appended",
+			conf: v2.Config{ExcludeClusters: []string{"staging"}},
+			env: map[string]string{
+				"GITOPS_ZOMBIES_EXCLUDE_CLUSTERS":  "dev, ,test",
+				"GITOPS_ZOMBIES_EXCLUDE_RESOURCES": "[{kind: Secret}]",
+			},
+			expected: v2.Config{
+				ExcludeClusters:  []string{"staging", "dev", "test"},
+				ExcludeResources: []v2.ResourceRule{{TypeMeta: metav1.TypeMeta{Kind: "Secret"}}},
+			},
+		},
+		{
+			name:        "invalid boolean",
+			env:         map[string]string{"GITOPS_ZOMBIES_FAIL": "maybe"},
+			expectedErr: `invalid GITOPS_ZOMBIES_FAIL: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:        "invalid duration",
+			env:         map[string]string{"GITOPS_ZOMBIES_MIN_AGE": "soon"},
+			expectedErr: `invalid GITOPS_ZOMBIES_MIN_AGE: error unmarshaling JSON: while decoding JSON: time: invalid duration "soon"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := test.conf.DeepCopy()
+			err := applyEnv(conf, func(name string) (string, bool) {
+				value, ok := test.env[name]
+				return value, ok
+			})
+
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, *conf, test.expected)
+		})
+	}
+}
+
+func TestParseEnvValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		typ         reflect.Type
+		value       string
+		expected    any
+		expectedErr bool
+	}{
+		{
+			name:     "string",
+			typ:      reflect.TypeFor[string](),
+			value:    "app=web",
+			expected: "app=web",
+		},
+		{
+			name:     "severity",
+			typ:      reflect.TypeFor[v2.Severity](),
+			value:    "critical",
+			expected: v2.SeverityCritical,
+		},
+		{
+			name:     "bool",
+			typ:      reflect.TypeFor[bool](),
+			value:    "1",
+			expected: true,
+		},
+		{
+			name:     "bool pointer set to false",
+			typ:      reflect.TypeFor[*bool](),
+			value:    "false",
+			expected: ptr.To(false),
+		},
+		{
+			name:        "invalid bool pointer",
+			typ:         reflect.TypeFor[*bool](),
+			value:       "nope",
+			expectedErr: true,
+		},
+		{
+			name:     "string list",
+			typ:      reflect.TypeFor[[]string](),
+			value:    "a, b,,c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "duration",
+			typ:      reflect.TypeFor[metav1.Duration](),
+			value:    "90m",
+			expected: metav1.Duration{Duration: 90 * time.Minute},
+		},
+		{
+			name:     "map",
+			typ:      reflect.TypeFor[map[v2.Severity]int](),
+			value:    "{critical: 0, warning: 10}",
+			expected: map[v2.Severity]int{v2.SeverityCritical: 0, v2.SeverityWarning: 10},
+		},
+		{
+			name:        "unknown field",
+			typ:         reflect.TypeFor[[]v2.ResourceRule](),
+			value:       "[{unknown: true}]",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := parseEnvValue(test.typ, test.value)
+			if test.expectedErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, parsed.Interface(), test.expected)
+		})
+	}
+}
+
+func TestLoadConfigValidatesExpressions(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+
+	assert.NilError(t, os.WriteFile(valid, []byte(`apiVersion: gitopszombies/v2
+kind: Config
+excludeResources:
+- expression: object.age() > duration('24h')
+`), 0o600))
+	assert.NilError(t, os.WriteFile(invalid, []byte(`apiVersion: gitopszombies/v2
+kind: Config
+severities:
+- expression: object.age(
+  severity: info
+`), 0o600))
+
+	conf, err := loadConfig([]string{valid}, false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(conf.ExcludeResources), 1)
+
+	_, err = loadConfig([]string{valid, invalid}, false)
+	assert.ErrorContains(t, err, "invalid config "+invalid)
+	assert.ErrorContains(t, err, "severities[0].expression")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,18 +10,20 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
 	k8sget "k8s.io/kubectl/pkg/cmd/get"
+	"k8s.io/utils/ptr"
 
-	gitopszombiesv1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/collector"
 	"github.com/raffis/gitops-zombies/pkg/detector"
 )
 
@@ -31,25 +34,53 @@ const (
 )
 
 type args struct {
-	gitopszombiesv1.Config
+	v2.Config
 
-	version bool
+	version     bool
+	contexts    []string
+	allContexts bool
 }
 
 const (
 	statusOK = iota
 	statusFail
 	statusZombiesDetected
+	statusWarningZombiesDetected
+	statusCriticalZombiesDetected
 )
 
 const (
 	statusAnnotation = "status"
 
-	flagExcludeCluster = "exclude-cluster"
-	flagFail           = "fail"
-	flagIncludeAll     = "include-all"
-	flagLabelSelector  = "selector"
-	flagNoStream       = "no-stream"
+	flagAllContexts          = "all-contexts"
+	flagAllowedExecCommands  = "allowed-exec-command"
+	flagAnnotate             = "annotate"
+	flagConfig               = "config"
+	flagConfigMapSelector    = "config-map-selector"
+	flagContexts             = "contexts"
+	flagDetectDrift          = "detect-drift"
+	flagDetectHelmReleases   = "detect-helm-releases"
+	flagDiscoverClusterAPI   = "discover-cluster-api"
+	flagExcludeCluster       = "exclude-cluster"
+	flagExcludeKinds         = "exclude-kinds"
+	flagExcludeNamespaces    = "exclude-namespaces"
+	flagFail                 = "fail"
+	flagFailOn               = "fail-on"
+	flagFluxSSAOwnership     = "flux-ssa-ownership"
+	flagImpersonateSAs       = "impersonate-service-accounts"
+	flagIncludeAll           = "include-all"
+	flagIncludeClusterScoped = "include-cluster-scoped"
+	flagIncludeNamespaces    = "namespaces"
+	flagKinds                = "kinds"
+	flagLabelSelector        = "selector"
+	flagMinAge               = "min-age"
+	flagNoStream             = "no-stream"
+	flagPolicy               = "policy"
+	flagReferences           = "references"
+	flagRequestSATokens      = "request-service-account-tokens"
+	flagSortBy               = "sort-by"
+	flagTree                 = "tree"
+	flagTrustedFieldManagers = "trusted-field-manager"
 )
 
 func main() {
@@ -95,18 +126,40 @@ const logo = `
 `
 
 func parseCliArgs() (*cobra.Command, error) {
-	flags := args{Config: gitopszombiesv1.Config{
-		TypeMeta:         metav1.TypeMeta{},
-		ExcludeClusters:  nil,
-		ExcludeResources: nil,
-		Fail:             false,
-		IncludeAll:       false,
-		LabelSelector:    "",
-		NoStream:         false,
+	flags := args{Config: v2.Config{
+		TypeMeta:                    metav1.TypeMeta{},
+		AllowedExecCommands:         nil,
+		Annotate:                    ptr.To(false),
+		ConfigMapSelector:           "",
+		DetectDrift:                 ptr.To(false),
+		DetectHelmReleases:          ptr.To(false),
+		DiscoverClusterAPI:          ptr.To(false),
+		ExcludeClusters:             nil,
+		ExcludeKinds:                nil,
+		ExcludeNamespaces:           nil,
+		ExcludeResources:            nil,
+		Fail:                        ptr.To(false),
+		FailOn:                      "",
+		FailThreshold:               nil,
+		FluxSSAOwnership:            ptr.To(false),
+		ImpersonateServiceAccounts:  ptr.To(false),
+		IncludeAll:                  ptr.To(false),
+		IncludeClusterScoped:        ptr.To(false),
+		IncludeNamespaces:           nil,
+		Kinds:                       nil,
+		LabelSelector:               "",
+		MinAge:                      metav1.Duration{},
+		NoStream:                    ptr.To(false),
+		Policy:                      "",
+		References:                  ptr.To(false),
+		RequestServiceAccountTokens: ptr.To(false),
+		SortBy:                      "",
+		Tree:                        ptr.To(false),
+		TrustedFieldManagers:        nil,
 	}}
 	kubeconfigArgs := genericclioptions.NewConfigFlags(false)
 	printFlags := k8sget.NewGetPrintFlags()
-	cfgFile := path.Join(homedir.HomeDir(), ".gitops-zombies.yaml")
+	cfgFiles := []string{path.Join(homedir.HomeDir(), ".gitops-zombies.yaml")}
 
 	rootCmd := &cobra.Command{
 		Use:           "gitops-zombies",
@@ -127,14 +180,23 @@ func parseCliArgs() (*cobra.Command, error) {
 				return nil
 			}
 
-			conf, err := loadConfig(cfgFile)
+			conf, err := loadConfig(cfgFiles, !cmd.Flags().Changed(flagConfig))
 			if err != nil {
 				return err
 			}
 
+			if err := applyEnv(conf, os.LookupEnv); err != nil {
+				return err
+			}
+
 			mergeConfigAndFlags(conf, flags.Config, cmd)
 
-			status, err := run(conf, kubeconfigArgs, printFlags)
+			contexts, err := resolveContexts(kubeconfigArgs, flags.contexts, flags.allContexts)
+			if err != nil {
+				return err
+			}
+
+			status, err := run(cmd.Context(), conf, kubeconfigArgs, printFlags, contexts)
 			if err != nil {
 				return err
 			}
@@ -164,119 +226,424 @@ func parseCliArgs() (*cobra.Command, error) {
 		return nil, err
 	}
 
-	rootCmd.Flags().StringVarP(&cfgFile, "config", "", cfgFile, "Config file")
+	rootCmd.PersistentFlags().
+		StringArrayVarP(&cfgFiles, flagConfig, "", cfgFiles, "Config file, can be repeated to merge multiple configs in order")
+	rootCmd.Flags().
+		StringVarP(&flags.ConfigMapSelector, flagConfigMapSelector, "", "", "Label selector of ConfigMaps on the flux cluster holding config fragments which exclude resources within their namespace")
 	rootCmd.Flags().
 		StringVarP(printFlags.OutputFormat, "output", "o", *printFlags.OutputFormat, fmt.Sprintf(`Output format. One of: (%s). See custom columns [https://kubernetes.io/docs/reference/kubectl/overview/#custom-columns], golang template [http://golang.org/pkg/text/template/#pkg-overview] and jsonpath template [https://kubernetes.io/docs/reference/kubectl/jsonpath/].`, strings.Join(printFlags.AllowedFormats(), ", ")))
 	rootCmd.Flags().BoolVarP(&flags.version, "version", "", flags.version, "Print version and exit")
 	rootCmd.Flags().
-		BoolVarP(&flags.IncludeAll, flagIncludeAll, "a", false, "Includes resources which are considered dynamic resources")
+		StringSliceVarP(&flags.contexts, flagContexts, "", nil, "Scan the flux clusters of multiple kubeconfig contexts and their remote clusters concurrently, implies --no-stream")
+	rootCmd.Flags().
+		BoolVarP(&flags.allContexts, flagAllContexts, "", false, "Scan the flux clusters of all kubeconfig contexts and their remote clusters concurrently, implies --no-stream")
+	rootCmd.Flags().
+		BoolVarP(flags.IncludeAll, flagIncludeAll, "a", false, "Includes resources which are considered dynamic resources")
 	rootCmd.Flags().
 		StringVarP(&flags.LabelSelector, flagLabelSelector, "l", "", "Label selector (Is used for all apis)")
 	rootCmd.Flags().
-		BoolVarP(&flags.NoStream, flagNoStream, "", false, "Display discovered resources at the end instead of live")
-	rootCmd.Flags().BoolVarP(&flags.Fail, flagFail, "", false, "Exit with an exit code > 0 if zombies are detected")
+		BoolVarP(flags.Annotate, flagAnnotate, "", false, "Add the findings (severity, last modification, references, ...) as gitops-zombies.io/* annotations to zombies printed by an output format")
+	rootCmd.Flags().
+		BoolVarP(flags.NoStream, flagNoStream, "", false, "Display discovered resources at the end instead of live")
+	rootCmd.Flags().
+		DurationVarP(&flags.MinAge.Duration, flagMinAge, "", 0, "Ignore resources younger than the given age (e.g. 24h)")
+	rootCmd.Flags().
+		StringVarP(&flags.SortBy, flagSortBy, "", "", fmt.Sprintf("Sort zombies, implies --no-stream. One of: (%s)", v2.SortByAge))
+	rootCmd.Flags().
+		StringVarP(&flags.Policy, flagPolicy, "", "", "Local directory holding rego policies (package gitopszombies) to ignore zombies, set their severity or attach messages")
+	rootCmd.Flags().
+		BoolVarP(flags.References, flagReferences, "", false, "Annotate zombies with the resources referencing them (mounts, image pull secrets, ingress tls, flux value references), implies --no-stream")
+	rootCmd.Flags().
+		BoolVarP(flags.Tree, flagTree, "", false, "Display zombies as tree grouped by namespace and owner including referenced resources, implies --no-stream")
+	rootCmd.Flags().BoolVarP(flags.Fail, flagFail, "", false, "Exit with an exit code > 0 if zombies are detected")
+	rootCmd.Flags().
+		StringVarP((*string)(&flags.FailOn), flagFailOn, "", "", fmt.Sprintf("Exit with an exit code > 0 if zombies of the given or a higher severity are detected. One of: (%s)", strings.Join(severityNames(), ", ")))
 	rootCmd.Flags().
 		StringSliceVarP(&flags.ExcludeClusters, flagExcludeCluster, "", []string{}, "Exclude cluster from zombie detection (default none)")
+	rootCmd.Flags().
+		StringArrayVarP(&flags.IncludeNamespaces, flagIncludeNamespaces, "", nil, "Restrict zombie detection to namespaces (glob or regexp), can be repeated")
+	rootCmd.Flags().
+		StringArrayVarP(&flags.ExcludeNamespaces, flagExcludeNamespaces, "", nil, "Exclude namespaces (glob or regexp) from zombie detection, can be repeated")
+	rootCmd.Flags().
+		StringSliceVarP(&flags.Kinds, flagKinds, "", nil, "Restrict zombie detection to kinds (kind, kind.group or resource.version.group), includes them even if they are considered dynamic resources")
+	rootCmd.Flags().
+		StringSliceVarP(&flags.ExcludeKinds, flagExcludeKinds, "", nil, "Exclude kinds (kind, kind.group or resource.version.group) from zombie detection")
+	rootCmd.Flags().
+		BoolVarP(flags.IncludeClusterScoped, flagIncludeClusterScoped, "", false, "Scan cluster scoped resources even if zombie detection is restricted to namespaces")
+	rootCmd.Flags().
+		BoolVarP(flags.DetectDrift, flagDetectDrift, "", false, "Report gitops managed resources which have been modified manually (kubectl or unknown field managers)")
+	rootCmd.Flags().
+		BoolVarP(flags.DetectHelmReleases, flagDetectHelmReleases, "", false, "Report releases installed by the helm cli which are not managed by a HelmRelease, once per release instead of each of its resources")
+	rootCmd.Flags().
+		BoolVarP(flags.ImpersonateServiceAccounts, flagImpersonateSAs, "", false, "Scan each cluster once per service account flux impersonates (spec.serviceAccountName) instead of your own identity, restricted to the service accounts of --namespace if set")
+	rootCmd.Flags().
+		BoolVarP(flags.RequestServiceAccountTokens, flagRequestSATokens, "", false, "Request tokens of the service accounts configured by generic workload identity kubeConfigs (TokenRequest, a write on the flux cluster) to scan their clusters")
+	rootCmd.Flags().
+		BoolVarP(flags.DiscoverClusterAPI, flagDiscoverClusterAPI, "", false, "Scan the clusters of all Cluster API clusters by their <name>-kubeconfig secret, including clusters no Kustomization or HelmRelease targets")
+	rootCmd.Flags().
+		BoolVarP(flags.FluxSSAOwnership, flagFluxSSAOwnership, "", false, "Consider resources server-side applied by a flux controller as managed even without flux labels")
+	rootCmd.Flags().
+		StringSliceVarP(&flags.TrustedFieldManagers, flagTrustedFieldManagers, "", []string{}, "Field managers which are not considered manual modifications (besides the builtin kubernetes and flux ones)")
+	rootCmd.Flags().
+		StringSliceVarP(&flags.AllowedExecCommands, flagAllowedExecCommands, "", []string{}, "Credential plugins which kubeconfigs of remote clusters may execute with your local credentials, none by default (aws, aws-iam-authenticator, gke-gcloud-auth-plugin and kubelogin are restricted to issuing tokens)")
+
+	for _, name := range []string{flagKinds, flagExcludeKinds} {
+		err = rootCmd.RegisterFlagCompletionFunc(
+			name,
+			func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return kindsCompletionFunc(kubeconfigArgs, toComplete)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = rootCmd.RegisterFlagCompletionFunc(
+		flagContexts,
+		func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return contextListCompletionFunc(kubeconfigArgs, toComplete)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rootCmd.RegisterFlagCompletionFunc(
+		flagFailOn,
+		cobra.FixedCompletions(severityNames(), cobra.ShellCompDirectiveNoFileComp),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCmd.MarkFlagsMutuallyExclusive("context", flagContexts, flagAllContexts)
+	rootCmd.AddCommand(newConfigCmd(&cfgFiles, kubeconfigArgs))
 
 	rootCmd.DisableAutoGenTag = true
 	rootCmd.SetOut(os.Stdout)
 	return rootCmd, nil
 }
 
-func loadConfig(configPath string) (*gitopszombiesv1.Config, error) {
-	_, err := os.Stat(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			klog.V(1).Infof("Can't find config file at %s", configPath)
-			return &gitopszombiesv1.Config{}, nil
-		}
-		return nil, err
+func mergeConfigAndFlags(conf *v2.Config, flags v2.Config, cmd *cobra.Command) {
+	// cmd line overrides config
+	if cmd.Flags().Changed(flagAllowedExecCommands) {
+		conf.AllowedExecCommands = flags.AllowedExecCommands
 	}
 
-	json, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
+	if cmd.Flags().Changed(flagAnnotate) {
+		conf.Annotate = flags.Annotate
 	}
 
-	err = gitopszombiesv1.AddToScheme(scheme.Scheme)
-	if err != nil {
-		return nil, err
+	if cmd.Flags().Changed(flagConfigMapSelector) {
+		conf.ConfigMapSelector = flags.ConfigMapSelector
 	}
 
-	obj, err := runtime.Decode(scheme.Codecs.UniversalDeserializer(), json)
-	if err != nil {
-		return nil, err
+	if cmd.Flags().Changed(flagDetectDrift) {
+		conf.DetectDrift = flags.DetectDrift
 	}
 
-	var cfg gitopszombiesv1.Config
-	switch o := obj.(type) {
-	case *gitopszombiesv1.Config:
-		cfg = *o
-	default:
-		err = errors.New("unsupported config")
-		return nil, err
+	if cmd.Flags().Changed(flagDetectHelmReleases) {
+		conf.DetectHelmReleases = flags.DetectHelmReleases
 	}
 
-	return &cfg, nil
-}
+	if cmd.Flags().Changed(flagDiscoverClusterAPI) {
+		conf.DiscoverClusterAPI = flags.DiscoverClusterAPI
+	}
 
-func mergeConfigAndFlags(conf *gitopszombiesv1.Config, flags gitopszombiesv1.Config, cmd *cobra.Command) {
-	// cmd line overrides config
 	if cmd.Flags().Changed(flagExcludeCluster) {
 		conf.ExcludeClusters = flags.ExcludeClusters
 	}
 
+	if cmd.Flags().Changed(flagExcludeKinds) {
+		conf.ExcludeKinds = flags.ExcludeKinds
+	}
+
+	if cmd.Flags().Changed(flagExcludeNamespaces) {
+		conf.ExcludeNamespaces = flags.ExcludeNamespaces
+	}
+
 	if cmd.Flags().Changed(flagFail) {
 		conf.Fail = flags.Fail
 	}
 
+	if cmd.Flags().Changed(flagFailOn) {
+		conf.FailOn = flags.FailOn
+	}
+
+	if cmd.Flags().Changed(flagFluxSSAOwnership) {
+		conf.FluxSSAOwnership = flags.FluxSSAOwnership
+	}
+
+	if cmd.Flags().Changed(flagImpersonateSAs) {
+		conf.ImpersonateServiceAccounts = flags.ImpersonateServiceAccounts
+	}
+
 	if cmd.Flags().Changed(flagIncludeAll) {
 		conf.IncludeAll = flags.IncludeAll
 	}
 
+	if cmd.Flags().Changed(flagIncludeClusterScoped) {
+		conf.IncludeClusterScoped = flags.IncludeClusterScoped
+	}
+
+	if cmd.Flags().Changed(flagIncludeNamespaces) {
+		conf.IncludeNamespaces = flags.IncludeNamespaces
+	}
+
+	if cmd.Flags().Changed(flagKinds) {
+		conf.Kinds = flags.Kinds
+	}
+
 	if cmd.Flags().Changed(flagLabelSelector) {
 		conf.LabelSelector = flags.LabelSelector
 	}
 
+	if cmd.Flags().Changed(flagMinAge) {
+		conf.MinAge = flags.MinAge
+	}
+
 	if cmd.Flags().Changed(flagNoStream) {
 		conf.NoStream = flags.NoStream
 	}
+
+	if cmd.Flags().Changed(flagPolicy) {
+		conf.Policy = flags.Policy
+	}
+
+	if cmd.Flags().Changed(flagReferences) {
+		conf.References = flags.References
+	}
+
+	if cmd.Flags().Changed(flagRequestSATokens) {
+		conf.RequestServiceAccountTokens = flags.RequestServiceAccountTokens
+	}
+
+	if cmd.Flags().Changed(flagSortBy) {
+		conf.SortBy = flags.SortBy
+	}
+
+	if cmd.Flags().Changed(flagTree) {
+		conf.Tree = flags.Tree
+	}
+
+	if cmd.Flags().Changed(flagTrustedFieldManagers) {
+		conf.TrustedFieldManagers = flags.TrustedFieldManagers
+	}
 }
 
 func run(
-	conf *gitopszombiesv1.Config,
+	ctx context.Context,
+	conf *v2.Config,
 	kubeconfigArgs *genericclioptions.ConfigFlags,
 	printFlags *k8sget.PrintFlags,
+	contexts []string,
 ) (int, error) {
-	// default processing
-	detect, err := detector.New(conf, kubeconfigArgs, printFlags)
-	if err != nil {
+	// expressions are compiled once and cached for the evaluation of all resources
+	if err := validateConfig(conf).ToAggregate(); err != nil {
 		return statusFail, err
 	}
-	resourceCount, allZombies, err := detect.DetectZombies()
-	if err != nil {
-		return statusFail, err
+
+	if conf.SortBy != "" {
+		// sorting requires all zombies to be known before printing
+		conf.NoStream = ptr.To(true)
+	}
+
+	if ptr.Deref(conf.References, false) {
+		// references can only be classified once all zombies are known
+		conf.NoStream = ptr.To(true)
+	}
+
+	if ptr.Deref(conf.Tree, false) {
+		if printFlags.OutputFormat != nil && *printFlags.OutputFormat != "" {
+			return statusFail, errors.New("tree view can not be combined with an output format")
+		}
+
+		conf.NoStream = ptr.To(true)
 	}
 
-	if conf.NoStream {
-		err = detect.PrintZombies(allZombies)
+	// default processing using the kubeconfig flags as they are
+	targets := []string{""}
+	if len(contexts) > 0 {
+		// contexts are scanned concurrently, streamed zombies would interleave
+		conf.NoStream = ptr.To(true)
+		targets = contexts
+	}
+
+	detections := make([]detection, len(targets))
+	var wg sync.WaitGroup
+	for i, name := range targets {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			detections[i] = detectContext(ctx, conf, kubeconfigArgs, printFlags, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	if len(detections) == 1 && detections[0].err != nil {
+		return statusFail, detections[0].err
+	}
+
+	failed := false
+	var resourceCount, totalZombies int
+	var tenants []detector.TenantSummary
+	var clusterErrors []detector.ClusterError
+	var clusters []string
+	var structured []unstructured.Unstructured
+	// zombies of multiple contexts are printed as a single list by structured output formats
+	printList := len(contexts) > 0 && printFlags.OutputFormat != nil && *printFlags.OutputFormat != ""
+	severities := make(map[v2.Severity]int)
+	for _, result := range detections {
+		if result.err != nil {
+			klog.Errorf("[%s] %v", result.context, result.err)
+			clusterErrors = append(clusterErrors, detector.ClusterError{Cluster: result.context, Err: result.err})
+			failed = true
+			continue
+		}
+
+		switch {
+		case printList:
+			structured = append(structured, result.detect.StructuredZombies(result.zombies)...)
+		case ptr.Deref(conf.NoStream, false):
+			if err := result.detect.PrintZombies(result.zombies); err != nil {
+				return statusFail, err
+			}
+		}
+
+		resourceCount += result.resourceCount
+		tenants = append(tenants, result.detect.Tenants()...)
+		clusterErrors = append(clusterErrors, result.detect.Errors()...)
+		clusters = append(clusters, result.detect.Clusters()...)
+		for _, zombies := range result.zombies {
+			totalZombies += len(zombies)
+			for _, zombie := range zombies {
+				severities[collector.Severity(zombie)]++
+			}
+		}
+	}
+
+	if printList {
+		p, err := printFlags.ToPrinter()
 		if err != nil {
 			return statusFail, err
 		}
+
+		if err := p.PrintObj(zombieList(structured), os.Stdout); err != nil {
+			return statusFail, err
+		}
+	}
+
+	for _, name := range conf.UnmatchedClusters(clusters) {
+		klog.Warningf("cluster %q of the config matches none of the clusters", name)
 	}
 
-	var totalZombies int
-	for _, zombies := range allZombies {
-		totalZombies += len(zombies)
+	if ptr.Deref(conf.NoStream, false) && printFlags.OutputFormat != nil && *printFlags.OutputFormat == "" {
+		fmt.Printf("\nSummary: %d resources found, %d zombies detected (%d critical, %d warning, %d info)\n",
+			resourceCount,
+			totalZombies,
+			severities[v2.SeverityCritical],
+			severities[v2.SeverityWarning],
+			severities[v2.SeverityInfo],
+		)
+
+		for _, tenant := range tenants {
+			fmt.Printf("[%s] %d resources visible, %d owned by the tenant, %d zombies detected\n",
+				tenant.Cluster,
+				tenant.Resources,
+				tenant.Owned,
+				tenant.Zombies,
+			)
+		}
+
+		printClusterErrors(os.Stdout, clusterErrors)
 	}
 
-	if conf.NoStream && printFlags.OutputFormat != nil && *printFlags.OutputFormat == "" {
-		fmt.Printf("\nSummary: %d resources found, %d zombies detected\n", resourceCount, totalZombies)
+	if failed {
+		return statusFail, nil
+	}
+
+	return runStatus(conf, severities, clusterErrors), nil
+}
+
+// runStatus returns the exit status of a run. Clusters which could not be scanned are only reported unless the run
+// fails on zombies, as the zombies of such clusters are unknown the run fails even if none were detected elsewhere.
+func runStatus(conf *v2.Config, severities map[v2.Severity]int, clusterErrors []detector.ClusterError) int {
+	failOnZombies := ptr.Deref(conf.Fail, false) || conf.FailOn != "" || len(conf.FailThreshold) > 0
+	if failOnZombies && len(clusterErrors) > 0 {
+		return statusFail
+	}
+
+	return failStatus(conf, severities)
+}
+
+// zombieList returns the zombies as a list.
+func zombieList(zombies []unstructured.Unstructured) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{Items: zombies}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+	if list.Items == nil {
+		list.Items = []unstructured.Unstructured{}
 	}
 
-	if conf.Fail && totalZombies > 0 {
-		return statusZombiesDetected, nil
+	return list
+}
+
+// printClusterErrors prints the clusters which could not be scanned or were scanned incompletely.
+func printClusterErrors(w io.Writer, clusterErrors []detector.ClusterError) {
+	if len(clusterErrors) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nErrors: %d clusters could not be scanned completely\n", len(clusterErrors))
+	for _, err := range clusterErrors {
+		fmt.Fprintln(w, err.Error())
+	}
+}
+
+// failStatus returns the exit status for the number of zombies per severity.
+// Plain --fail exits with statusZombiesDetected, the status depends on the highest severity failing the run
+// only if --fail-on or a fail threshold is used.
+func failStatus(conf *v2.Config, severities map[v2.Severity]int) int {
+	failing := false
+	var highest v2.Severity
+	for severity, count := range severities {
+		if count == 0 {
+			continue
+		}
+
+		threshold, hasThreshold := conf.FailThreshold[severity]
+		if !ptr.Deref(conf.Fail, false) &&
+			(conf.FailOn == "" || severity.Compare(conf.FailOn) < 0) &&
+			(!hasThreshold || count <= threshold) {
+			continue
+		}
+
+		if !failing || severity.Compare(highest) > 0 {
+			highest = severity
+		}
+		failing = true
+	}
+
+	switch {
+	case !failing:
+		return statusOK
+	case conf.FailOn == "" && len(conf.FailThreshold) == 0:
+		return statusZombiesDetected
+	case highest == v2.SeverityCritical:
+		return statusCriticalZombiesDetected
+	case highest == v2.SeverityWarning:
+		return statusWarningZombiesDetected
+	default:
+		return statusZombiesDetected
+	}
+}
+
+func severityNames() []string {
+	names := make([]string, 0, len(v2.Severities))
+	for _, severity := range v2.Severities {
+		names = append(names, string(severity))
 	}
 
-	return statusOK, nil
+	return names
 }
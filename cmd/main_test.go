@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/detector"
+)
+
+func TestFailStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       v2.Config
+		severities map[v2.Severity]int
+		expected   int
+	}{
+		{
+			name:       "no fail options",
+			severities: map[v2.Severity]int{v2.SeverityCritical: 1},
+			expected:   statusOK,
+		},
+		{
+			name:     "fail without zombies",
+			conf:     v2.Config{Fail: ptr.To(true)},
+			expected: statusOK,
+		},
+		{
+			name:       "fail ignores severities",
+			conf:       v2.Config{Fail: ptr.To(true)},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 2, v2.SeverityCritical: 1},
+			expected:   statusZombiesDetected,
+		},
+		{
+			name:       "fail disabled explicitly",
+			conf:       v2.Config{Fail: ptr.To(false)},
+			severities: map[v2.Severity]int{v2.SeverityCritical: 1},
+			expected:   statusOK,
+		},
+		{
+			name:       "zero counts are ignored",
+			conf:       v2.Config{Fail: ptr.To(true)},
+			severities: map[v2.Severity]int{v2.SeverityCritical: 0},
+			expected:   statusOK,
+		},
+		{
+			name:       "fail on below the given severity",
+			conf:       v2.Config{FailOn: v2.SeverityWarning},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 5},
+			expected:   statusOK,
+		},
+		{
+			name:       "fail on the given severity",
+			conf:       v2.Config{FailOn: v2.SeverityWarning},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 5, v2.SeverityWarning: 1},
+			expected:   statusWarningZombiesDetected,
+		},
+		{
+			name:       "fail on a higher severity",
+			conf:       v2.Config{FailOn: v2.SeverityWarning},
+			severities: map[v2.Severity]int{v2.SeverityWarning: 1, v2.SeverityCritical: 1},
+			expected:   statusCriticalZombiesDetected,
+		},
+		{
+			name:       "fail combined with fail on uses severities",
+			conf:       v2.Config{Fail: ptr.To(true), FailOn: v2.SeverityCritical},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 1},
+			expected:   statusZombiesDetected,
+		},
+		{
+			name:       "threshold not exceeded",
+			conf:       v2.Config{FailThreshold: map[v2.Severity]int{v2.SeverityWarning: 10}},
+			severities: map[v2.Severity]int{v2.SeverityWarning: 10},
+			expected:   statusOK,
+		},
+		{
+			name:       "threshold exceeded",
+			conf:       v2.Config{FailThreshold: map[v2.Severity]int{v2.SeverityWarning: 10}},
+			severities: map[v2.Severity]int{v2.SeverityWarning: 11, v2.SeverityInfo: 100},
+			expected:   statusWarningZombiesDetected,
+		},
+		{
+			name: "fail on and threshold",
+			conf: v2.Config{
+				FailOn:        v2.SeverityCritical,
+				FailThreshold: map[v2.Severity]int{v2.SeverityInfo: 0},
+			},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 1, v2.SeverityWarning: 3},
+			expected:   statusZombiesDetected,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, failStatus(&test.conf, test.severities), test.expected)
+		})
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	clusterErrors := []detector.ClusterError{{Cluster: "secret apps/prod", Err: errors.New("unreachable")}}
+	tests := []struct {
+		name          string
+		conf          v2.Config
+		severities    map[v2.Severity]int
+		clusterErrors []detector.ClusterError
+		expected      int
+	}{
+		{
+			name:     "no zombies",
+			conf:     v2.Config{Fail: ptr.To(true)},
+			expected: statusOK,
+		},
+		{
+			name:       "zombies",
+			conf:       v2.Config{Fail: ptr.To(true)},
+			severities: map[v2.Severity]int{v2.SeverityInfo: 1},
+			expected:   statusZombiesDetected,
+		},
+		{
+			name:          "cluster errors without zombies",
+			conf:          v2.Config{Fail: ptr.To(true)},
+			clusterErrors: clusterErrors,
+			expected:      statusFail,
+		},
+		{
+			name:          "cluster errors with zombies",
+			conf:          v2.Config{FailOn: v2.SeverityInfo},
+			severities:    map[v2.Severity]int{v2.SeverityCritical: 1},
+			clusterErrors: clusterErrors,
+			expected:      statusFail,
+		},
+		{
+			name:          "cluster errors with threshold",
+			conf:          v2.Config{FailThreshold: map[v2.Severity]int{v2.SeverityWarning: 10}},
+			clusterErrors: clusterErrors,
+			expected:      statusFail,
+		},
+		{
+			name:          "cluster errors without fail options",
+			severities:    map[v2.Severity]int{v2.SeverityCritical: 1},
+			clusterErrors: clusterErrors,
+			expected:      statusOK,
+		},
+		{
+			name:          "cluster errors with fail disabled",
+			conf:          v2.Config{Fail: ptr.To(false)},
+			clusterErrors: clusterErrors,
+			expected:      statusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, runStatus(&test.conf, test.severities, test.clusterErrors), test.expected)
+		})
+	}
+}
+
+func TestPrintClusterErrors(t *testing.T) {
+	var buf bytes.Buffer
+	printClusterErrors(&buf, nil)
+	assert.Equal(t, buf.String(), "")
+
+	printClusterErrors(&buf, []detector.ClusterError{
+		{Cluster: "secret apps/prod", Err: errors.New("could not connect to cluster: unreachable")},
+		{Cluster: "staging", Err: errors.New("forbidden")},
+	})
+	assert.Equal(t, buf.String(), `
+Errors: 2 clusters could not be scanned completely
+[secret apps/prod] could not connect to cluster: unreachable
+[staging] forbidden
+`)
+}
+
+func TestSeverityNames(t *testing.T) {
+	assert.DeepEqual(t, severityNames(), []string{"info", "warning", "critical"})
+}
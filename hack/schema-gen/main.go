@@ -0,0 +1,53 @@
+// Command schema-gen generates the JSON schema of the config api version within the current directory.
+// It is invoked by go:generate from the api packages.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/jsonschema"
+)
+
+// schemaFile is the name of the generated schema which is embedded by the api packages.
+const schemaFile = "config.schema.json"
+
+var versions = map[string]struct {
+	gv  schema.GroupVersion
+	obj runtime.Object
+}{
+	v1.SchemeGroupVersion.Version: {gv: v1.SchemeGroupVersion, obj: &v1.Config{}},
+	v2.SchemeGroupVersion.Version: {gv: v2.SchemeGroupVersion, obj: &v2.Config{}},
+}
+
+func main() {
+	if err := generate(); err != nil {
+		fmt.Fprintf(os.Stderr, "schema-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate() error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	version, ok := versions[filepath.Base(dir)]
+	if !ok {
+		return fmt.Errorf("unknown api version %s", filepath.Base(dir))
+	}
+
+	b, err := jsonschema.Generate(dir, version.gv, version.obj)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, schemaFile), b, 0o644)
+}
@@ -13,21 +13,46 @@ import (
 type Config struct {
 	metav1.TypeMeta `json:",inline"`
 
-	ExcludeClusters  []string           `json:"excludeClusters,omitempty"`
+	// DetectDrift reports gitops managed resources which have been modified manually (kubectl or unknown field managers).
+	DetectDrift bool `json:"detectDrift,omitempty"`
+	// ExcludeClusters excludes clusters from zombie detection.
+	ExcludeClusters []string `json:"excludeClusters,omitempty"`
+	// ExcludeResources excludes resources matching any of the rules from zombie detection.
 	ExcludeResources []ExcludeResources `json:"excludeResources,omitempty"`
-	Fail             bool               `json:"fail,omitempty"`
-	IncludeAll       bool               `json:"includeAll,omitempty"`
-	LabelSelector    string             `json:"selector,omitempty"`
-	NoStream         bool               `json:"noStream,omitempty"`
+	// Fail exits with an exit code > 0 if zombies are detected.
+	Fail bool `json:"fail,omitempty"`
+	// FluxSSAOwnership considers resources server-side applied by a flux controller as managed even without flux labels.
+	FluxSSAOwnership bool `json:"fluxSSAOwnership,omitempty"`
+	// IncludeAll includes resources which are considered dynamic resources.
+	IncludeAll bool `json:"includeAll,omitempty"`
+	// LabelSelector is used while listing all apis.
+	LabelSelector string `json:"selector,omitempty"`
+	// MinAge ignores resources younger than the given age (e.g. 24h).
+	MinAge metav1.Duration `json:"minAge,omitempty"`
+	// NoStream displays discovered resources at the end instead of live.
+	NoStream bool `json:"noStream,omitempty"`
+	// References annotates zombies with the resources referencing them.
+	References bool `json:"references,omitempty"`
+	// SortBy sorts zombies, implies noStream. One of: age.
+	SortBy string `json:"sortBy,omitempty"`
+	// Tree displays zombies as tree grouped by namespace and owner including referenced resources, implies noStream.
+	Tree bool `json:"tree,omitempty"`
+	// TrustedFieldManagers are field managers which are not considered manual modifications.
+	TrustedFieldManagers []string `json:"trustedFieldManagers,omitempty"`
 }
 
 // ExcludeResources configures filters to exclude resources from zombies list.
 type ExcludeResources struct {
 	metav1.TypeMeta `json:",inline"`
 
-	Cluster     string            `json:"cluster,omitempty"`
+	// Cluster the rule applies to (regexp), applies to all clusters if empty.
+	Cluster string `json:"cluster,omitempty"`
+	// Annotations the resource must have, values are regular expressions.
 	Annotations map[string]string `json:"annotations,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
-	Name        string            `json:"name,omitempty"`
-	Namespace   string            `json:"namespace,omitempty"`
+	// Labels the resource must have, values are regular expressions.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Name of the resource (regexp).
+	Name string `json:"name,omitempty"`
+	// Namespace of the resource (regexp).
+	Namespace string `json:"namespace,omitempty"`
 }
@@ -41,6 +41,12 @@ func (in *Config) DeepCopyInto(out *Config) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	out.MinAge = in.MinAge
+	if in.TrustedFieldManagers != nil {
+		in, out := &in.TrustedFieldManagers, &out.TrustedFieldManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -0,0 +1,56 @@
+package v2
+
+import (
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	v1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+)
+
+func addConversionFuncs(scheme *runtime.Scheme) error {
+	return scheme.AddConversionFunc((*v1.Config)(nil), (*Config)(nil), func(a, b any, scope conversion.Scope) error {
+		return ConvertV1Config(a.(*v1.Config), b.(*Config), scope)
+	})
+}
+
+// ConvertV1Config converts a v1 config into a v2 config.
+func ConvertV1Config(in *v1.Config, out *Config, _ conversion.Scope) error {
+	out.TypeMeta.APIVersion = SchemeGroupVersion.String()
+	out.TypeMeta.Kind = "Config"
+	out.DetectDrift = convertV1Bool(in.DetectDrift)
+	out.ExcludeClusters = in.ExcludeClusters
+	out.Fail = convertV1Bool(in.Fail)
+	out.FluxSSAOwnership = convertV1Bool(in.FluxSSAOwnership)
+	out.IncludeAll = convertV1Bool(in.IncludeAll)
+	out.LabelSelector = in.LabelSelector
+	out.MinAge = in.MinAge
+	out.NoStream = convertV1Bool(in.NoStream)
+	out.References = convertV1Bool(in.References)
+	out.SortBy = in.SortBy
+	out.Tree = convertV1Bool(in.Tree)
+	out.TrustedFieldManagers = in.TrustedFieldManagers
+
+	out.ExcludeResources = nil
+	for _, exclusion := range in.ExcludeResources {
+		out.ExcludeResources = append(out.ExcludeResources, ResourceRule{
+			TypeMeta:    exclusion.TypeMeta,
+			Cluster:     exclusion.Cluster,
+			Annotations: exclusion.Annotations,
+			Labels:      exclusion.Labels,
+			Name:        exclusion.Name,
+			Namespace:   exclusion.Namespace,
+		})
+	}
+
+	return nil
+}
+
+// convertV1Bool converts a bool of a v1 config, false is not distinguishable from an unset field in v1.
+func convertV1Bool(b bool) *bool {
+	if !b {
+		return nil
+	}
+
+	return ptr.To(true)
+}
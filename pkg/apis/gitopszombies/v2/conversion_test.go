@@ -0,0 +1,95 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	v1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+)
+
+func TestConvertV1Config(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       v1.Config
+		expected Config
+	}{
+		{
+			name:     "empty config",
+			in:       v1.Config{},
+			expected: Config{TypeMeta: metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "Config"}},
+		},
+		{
+			name: "all fields",
+			in: v1.Config{
+				TypeMeta:             metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String(), Kind: "Config"},
+				DetectDrift:          true,
+				ExcludeClusters:      []string{"staging"},
+				Fail:                 true,
+				FluxSSAOwnership:     true,
+				IncludeAll:           true,
+				LabelSelector:        "app=web",
+				MinAge:               metav1.Duration{Duration: time.Hour},
+				NoStream:             true,
+				References:           true,
+				SortBy:               SortByAge,
+				Tree:                 true,
+				TrustedFieldManagers: []string{"my-operator"},
+			},
+			expected: Config{
+				TypeMeta:             metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "Config"},
+				DetectDrift:          ptr.To(true),
+				ExcludeClusters:      []string{"staging"},
+				Fail:                 ptr.To(true),
+				FluxSSAOwnership:     ptr.To(true),
+				IncludeAll:           ptr.To(true),
+				LabelSelector:        "app=web",
+				MinAge:               metav1.Duration{Duration: time.Hour},
+				NoStream:             ptr.To(true),
+				References:           ptr.To(true),
+				SortBy:               SortByAge,
+				Tree:                 ptr.To(true),
+				TrustedFieldManagers: []string{"my-operator"},
+			},
+		},
+		{
+			name: "exclusions become resource rules",
+			in: v1.Config{
+				ExcludeResources: []v1.ExcludeResources{
+					{
+						TypeMeta:    metav1.TypeMeta{APIVersion: "velero.io/v1", Kind: "Backup"},
+						Cluster:     "management",
+						Annotations: map[string]string{"owner": "velero"},
+						Labels:      map[string]string{"app": "velero"},
+						Name:        "velero-capi-backup-.*",
+						Namespace:   "velero",
+					},
+				},
+			},
+			expected: Config{
+				TypeMeta: metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "Config"},
+				ExcludeResources: []ResourceRule{
+					{
+						TypeMeta:    metav1.TypeMeta{APIVersion: "velero.io/v1", Kind: "Backup"},
+						Cluster:     "management",
+						Annotations: map[string]string{"owner": "velero"},
+						Labels:      map[string]string{"app": "velero"},
+						Name:        "velero-capi-backup-.*",
+						Namespace:   "velero",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out Config
+			assert.NilError(t, ConvertV1Config(&test.in, &out, nil))
+			assert.DeepEqual(t, out, test.expected)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package v2
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultConfigFile = "defaults/default.yaml"
+	presetsDir        = "defaults/presets"
+)
+
+//go:embed defaults
+var defaultsFS embed.FS
+
+// defaults holds the builtin default config and the blacklist presets by name.
+var defaults = sync.OnceValues(func() (*Config, map[string]*Config) {
+	conf, err := decodeEmbeddedConfig(defaultConfigFile)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := defaultsFS.ReadDir(presetsDir)
+	if err != nil {
+		panic(err)
+	}
+
+	presets := make(map[string]*Config, len(entries))
+	for _, entry := range entries {
+		preset, err := decodeEmbeddedConfig(path.Join(presetsDir, entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		presets[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = preset
+	}
+
+	return conf, presets
+})
+
+func decodeEmbeddedConfig(name string) (*Config, error) {
+	data, err := defaultsFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var conf Config
+	if err := yaml.UnmarshalStrict(data, &conf); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	if conf.GroupVersionKind() != SchemeGroupVersion.WithKind("Config") {
+		return nil, fmt.Errorf("unsupported config %s in %s", conf.GroupVersionKind(), name)
+	}
+
+	return &conf, nil
+}
+
+// DefaultBlacklist returns the builtin resources which are considered dynamic.
+func DefaultBlacklist() []GroupVersionResource {
+	conf, _ := defaults()
+	return slices.Clone(conf.Blacklist)
+}
+
+// BlacklistPresets returns the names of all blacklist presets.
+func BlacklistPresets() []string {
+	_, presets := defaults()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+// BlacklistPreset returns the resources of a blacklist preset.
+func BlacklistPreset(name string) ([]GroupVersionResource, bool) {
+	_, presets := defaults()
+	preset, ok := presets[name]
+	if !ok {
+		return nil, false
+	}
+
+	return slices.Clone(preset.Blacklist), true
+}
+
+// IsBlacklisted returns true if a resource is considered dynamic by the builtin blacklist, the enabled presets or
+// the blacklist of the config and is not excluded from the blacklist.
+func (c *Config) IsBlacklisted(gvr schema.GroupVersionResource) bool {
+	matches := func(entry GroupVersionResource) bool {
+		return entry.Matches(gvr)
+	}
+
+	if slices.ContainsFunc(c.ExcludeBlacklist, matches) {
+		return false
+	}
+
+	if slices.ContainsFunc(DefaultBlacklist(), matches) || slices.ContainsFunc(c.Blacklist, matches) {
+		return true
+	}
+
+	for _, name := range c.BlacklistPresets {
+		if preset, ok := BlacklistPreset(name); ok && slices.ContainsFunc(preset, matches) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package v2
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestBlacklistPresets(t *testing.T) {
+	assert.DeepEqual(t, BlacklistPresets(), []string{"calico", "cert-manager", "cilium", "istio", "karpenter", "velero"})
+
+	for _, name := range BlacklistPresets() {
+		preset, ok := BlacklistPreset(name)
+		assert.Assert(t, ok)
+		assert.Assert(t, len(preset) > 0, name)
+		assert.Assert(t, len(validateBlacklist(preset, nil)) == 0, name)
+	}
+}
+
+func TestIsBlacklisted(t *testing.T) {
+	leases := schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1beta1", Resource: "leases"}
+	pvcs := schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+	identities := schema.GroupVersionResource{Group: "cilium.io", Version: "v2", Resource: "ciliumidentities"}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	conf := &Config{}
+	assert.Assert(t, conf.IsBlacklisted(leases))
+	assert.Assert(t, conf.IsBlacklisted(pvcs))
+	assert.Assert(t, !conf.IsBlacklisted(identities))
+	assert.Assert(t, !conf.IsBlacklisted(deployments))
+
+	conf = &Config{
+		BlacklistPresets: []string{"cilium"},
+		Blacklist:        []GroupVersionResource{{Group: "apps", Resource: "deployments"}},
+		ExcludeBlacklist: []GroupVersionResource{{Resource: "persistentvolumeclaims"}},
+	}
+	assert.Assert(t, conf.IsBlacklisted(leases))
+	assert.Assert(t, !conf.IsBlacklisted(pvcs))
+	assert.Assert(t, conf.IsBlacklisted(identities))
+	assert.Assert(t, conf.IsBlacklisted(deployments))
+}
@@ -0,0 +1,6 @@
+// Package v2 contains the v2 API definitions for gitops-zombies configuration.
+//
+// +k8s:deepcopy-gen=package
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=gitopszombies
+package v2
@@ -0,0 +1,252 @@
+package v2
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+)
+
+// namespaceGlob matches namespace patterns consisting of namespace name characters and wildcards only.
+var namespaceGlob = regexp.MustCompile(`^[a-z0-9-]*[*?][a-z0-9*?-]*$`)
+
+// NamespacePattern returns the regular expression of a namespace pattern.
+// Patterns consisting of namespace name characters and the wildcards * and ? only are globs (kube-*),
+// all others are regular expressions.
+func NamespacePattern(expr string) string {
+	if !namespaceGlob.MatchString(expr) {
+		return expr
+	}
+
+	return strings.NewReplacer("*", ".*", "?", ".").Replace(expr)
+}
+
+// CompileNamespacePatterns compiles namespace patterns (see NamespacePattern) matching whole namespace names.
+func CompileNamespacePatterns(exprs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(`^` + NamespacePattern(expr) + `$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", expr, err)
+		}
+
+		patterns = append(patterns, re)
+	}
+
+	return patterns, nil
+}
+
+// ForCluster returns the effective config for a cluster with all matching cluster overrides applied.
+func (c *Config) ForCluster(cluster string) *Config {
+	conf := c.DeepCopy()
+	conf.Clusters = nil
+
+	for _, override := range c.Clusters {
+		if match, err := regexp.MatchString(`^`+override.Name+`$`, cluster); err != nil || !match {
+			continue
+		}
+
+		conf.ExcludeNamespaces = append(conf.ExcludeNamespaces, override.ExcludeNamespaces...)
+		conf.ExcludeResources = append(conf.ExcludeResources, override.ExcludeResources...)
+		conf.IncludeNamespaces = append(conf.IncludeNamespaces, override.IncludeNamespaces...)
+		conf.IncludeResources = append(conf.IncludeResources, override.IncludeResources...)
+		conf.LabelSelectors = append(conf.LabelSelectors, override.LabelSelectors...)
+		conf.Severities = append(conf.Severities, override.Severities...)
+
+		if override.IncludeAll != nil {
+			conf.IncludeAll = ptr.To(*override.IncludeAll)
+		}
+
+		if override.LabelSelector != "" {
+			conf.LabelSelector = override.LabelSelector
+		}
+	}
+
+	return conf
+}
+
+// UnmatchedClusters returns the excludeClusters and the names (regexp) of the cluster overrides which match none
+// of the clusters.
+func (c *Config) UnmatchedClusters(clusters []string) []string {
+	var unmatched []string
+	for _, name := range c.ExcludeClusters {
+		if !slices.Contains(clusters, name) {
+			unmatched = append(unmatched, name)
+		}
+	}
+
+	for _, override := range c.Clusters {
+		re, err := regexp.Compile(`^` + override.Name + `$`)
+		if err != nil {
+			continue
+		}
+
+		if !slices.ContainsFunc(clusters, re.MatchString) {
+			unmatched = append(unmatched, override.Name)
+		}
+	}
+
+	return unmatched
+}
+
+// Merge merges another config into the config.
+// Lists are appended to the existing ones while set values replace them, including booleans set to false.
+func (c *Config) Merge(other *Config) {
+	c.AllowedExecCommands = append(c.AllowedExecCommands, other.AllowedExecCommands...)
+	c.Blacklist = append(c.Blacklist, other.Blacklist...)
+	c.BlacklistPresets = append(c.BlacklistPresets, other.BlacklistPresets...)
+	c.Clusters = append(c.Clusters, other.Clusters...)
+	c.ExcludeBlacklist = append(c.ExcludeBlacklist, other.ExcludeBlacklist...)
+	c.ExcludeClusters = append(c.ExcludeClusters, other.ExcludeClusters...)
+	c.ExcludeKinds = append(c.ExcludeKinds, other.ExcludeKinds...)
+	c.ExcludeNamespaces = append(c.ExcludeNamespaces, other.ExcludeNamespaces...)
+	c.ExcludeResources = append(c.ExcludeResources, other.ExcludeResources...)
+	c.IgnorePresets = append(c.IgnorePresets, other.IgnorePresets...)
+	c.IncludeNamespaces = append(c.IncludeNamespaces, other.IncludeNamespaces...)
+	c.IncludeResources = append(c.IncludeResources, other.IncludeResources...)
+	c.Kinds = append(c.Kinds, other.Kinds...)
+	c.LabelSelectors = append(c.LabelSelectors, other.LabelSelectors...)
+	c.Severities = append(c.Severities, other.Severities...)
+	c.TrustedFieldManagers = append(c.TrustedFieldManagers, other.TrustedFieldManagers...)
+
+	if other.Annotate != nil {
+		c.Annotate = ptr.To(*other.Annotate)
+	}
+
+	if other.DetectDrift != nil {
+		c.DetectDrift = ptr.To(*other.DetectDrift)
+	}
+
+	if other.DetectHelmReleases != nil {
+		c.DetectHelmReleases = ptr.To(*other.DetectHelmReleases)
+	}
+
+	if other.DiscoverClusterAPI != nil {
+		c.DiscoverClusterAPI = ptr.To(*other.DiscoverClusterAPI)
+	}
+
+	if other.Fail != nil {
+		c.Fail = ptr.To(*other.Fail)
+	}
+
+	if other.FluxSSAOwnership != nil {
+		c.FluxSSAOwnership = ptr.To(*other.FluxSSAOwnership)
+	}
+
+	if other.ImpersonateServiceAccounts != nil {
+		c.ImpersonateServiceAccounts = ptr.To(*other.ImpersonateServiceAccounts)
+	}
+
+	if other.IncludeAll != nil {
+		c.IncludeAll = ptr.To(*other.IncludeAll)
+	}
+
+	if other.IncludeClusterScoped != nil {
+		c.IncludeClusterScoped = ptr.To(*other.IncludeClusterScoped)
+	}
+
+	if other.NoStream != nil {
+		c.NoStream = ptr.To(*other.NoStream)
+	}
+
+	if other.References != nil {
+		c.References = ptr.To(*other.References)
+	}
+
+	if other.RequestServiceAccountTokens != nil {
+		c.RequestServiceAccountTokens = ptr.To(*other.RequestServiceAccountTokens)
+	}
+
+	if other.Tree != nil {
+		c.Tree = ptr.To(*other.Tree)
+	}
+
+	if other.ConfigMapSelector != "" {
+		c.ConfigMapSelector = other.ConfigMapSelector
+	}
+
+	if other.FailOn != "" {
+		c.FailOn = other.FailOn
+	}
+
+	for severity, threshold := range other.FailThreshold {
+		if c.FailThreshold == nil {
+			c.FailThreshold = make(map[Severity]int)
+		}
+
+		c.FailThreshold[severity] = threshold
+	}
+
+	if other.LabelSelector != "" {
+		c.LabelSelector = other.LabelSelector
+	}
+
+	if other.MinAge.Duration != 0 {
+		c.MinAge = other.MinAge
+	}
+
+	if other.Policy != "" {
+		c.Policy = other.Policy
+	}
+
+	if other.SortBy != "" {
+		c.SortBy = other.SortBy
+	}
+}
+
+// Matches returns true if gvr is the referenced api resource.
+func (r GroupVersionResource) Matches(gvr schema.GroupVersionResource) bool {
+	return r.Group == gvr.Group && r.Resource == gvr.Resource && (r.Version == "" || r.Version == gvr.Version)
+}
+
+// Compare returns -1, 0 or +1 depending on whether the severity is lower, equal or higher than other.
+// Unknown severities are lower than all known ones.
+func (s Severity) Compare(other Severity) int {
+	return cmp.Compare(slices.Index(Severities, s), slices.Index(Severities, other))
+}
+
+// MatchesKind returns true if an api resource matches a kind in the notation of kubectl, either the kind or
+// resource name (deployment, deployments, deploy), kind.group (deployment.apps) or resource.version.group
+// (deployments.v1.apps). Subresources never match.
+func MatchesKind(kind string, gv schema.GroupVersion, resource metav1.APIResource) bool {
+	if strings.Contains(resource.Name, "/") {
+		return false
+	}
+
+	name, rest, qualified := strings.Cut(strings.ToLower(kind), ".")
+	if name != strings.ToLower(resource.Kind) &&
+		name != resource.Name &&
+		name != resource.SingularName &&
+		!slices.Contains(resource.ShortNames, name) {
+		return false
+	}
+
+	if !qualified {
+		return true
+	}
+
+	return rest == gv.Group || rest == gv.Version+"."+gv.Group || (gv.Group == "" && rest == gv.Version)
+}
+
+// ServesKind returns true if any of the served api resources matches the kind (see MatchesKind).
+func ServesKind(lists []*metav1.APIResourceList, kind string) bool {
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, res := range list.APIResources {
+			if MatchesKind(kind, gv, res) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
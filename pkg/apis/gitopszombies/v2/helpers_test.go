@@ -0,0 +1,169 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     Config
+		other    Config
+		expected Config
+	}{
+		{
+			name:     "empty configs",
+			expected: Config{},
+		},
+		{
+			name:     "unset values are kept",
+			conf:     Config{Fail: ptr.To(true), LabelSelector: "app=web", MinAge: metav1.Duration{Duration: time.Hour}},
+			other:    Config{},
+			expected: Config{Fail: ptr.To(true), LabelSelector: "app=web", MinAge: metav1.Duration{Duration: time.Hour}},
+		},
+		{
+			name:     "set values replace existing ones",
+			conf:     Config{Fail: ptr.To(true), LabelSelector: "app=web"},
+			other:    Config{Fail: ptr.To(false), LabelSelector: "app=api", SortBy: SortByAge},
+			expected: Config{Fail: ptr.To(false), LabelSelector: "app=api", SortBy: SortByAge},
+		},
+		{
+			name:     "booleans set to false reset earlier ones",
+			conf:     Config{DetectDrift: ptr.To(true), IncludeAll: ptr.To(true), Tree: ptr.To(true)},
+			other:    Config{DetectDrift: ptr.To(false), Tree: ptr.To(false)},
+			expected: Config{DetectDrift: ptr.To(false), IncludeAll: ptr.To(true), Tree: ptr.To(false)},
+		},
+		{
+			name:     "lists are appended",
+			conf:     Config{ExcludeClusters: []string{"staging"}, Kinds: []string{"deployments"}},
+			other:    Config{ExcludeClusters: []string{"dev"}, IgnorePresets: []string{"cert-manager"}},
+			expected: Config{ExcludeClusters: []string{"staging", "dev"}, IgnorePresets: []string{"cert-manager"}, Kinds: []string{"deployments"}},
+		},
+		{
+			name:     "fail thresholds are merged per severity",
+			conf:     Config{FailThreshold: map[Severity]int{SeverityInfo: 5, SeverityCritical: 1}},
+			other:    Config{FailThreshold: map[Severity]int{SeverityCritical: 0}},
+			expected: Config{FailThreshold: map[Severity]int{SeverityInfo: 5, SeverityCritical: 0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := test.conf.DeepCopy()
+			conf.Merge(&test.other)
+			assert.DeepEqual(t, *conf, test.expected)
+		})
+	}
+}
+
+func TestMergeDoesNotAlias(t *testing.T) {
+	conf := &Config{}
+	other := &Config{Fail: ptr.To(true)}
+	conf.Merge(other)
+
+	*other.Fail = false
+	assert.Assert(t, *conf.Fail)
+}
+
+func TestNamespacePattern(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "default", expected: "default"},
+		{expr: "kube-*", expected: "kube-.*"},
+		{expr: "team-?", expected: "team-."},
+		{expr: "*-system", expected: ".*-system"},
+		{expr: "team-(a|b)", expected: "team-(a|b)"},
+		{expr: "velero.*", expected: "velero.*"},
+		{expr: "velero[0-9]", expected: "velero[0-9]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			assert.Equal(t, NamespacePattern(test.expr), test.expected)
+		})
+	}
+}
+
+func TestCompileNamespacePatterns(t *testing.T) {
+	patterns, err := CompileNamespacePatterns([]string{"kube-*", "team-(a|b)"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(patterns), 2)
+
+	assert.Assert(t, patterns[0].MatchString("kube-system"))
+	assert.Assert(t, !patterns[0].MatchString("my-kube-system"))
+	assert.Assert(t, patterns[1].MatchString("team-a"))
+	assert.Assert(t, !patterns[1].MatchString("team-ab"))
+
+	_, err = CompileNamespacePatterns([]string{"team-(a"})
+	assert.ErrorContains(t, err, `invalid pattern "team-(a"`)
+}
+
+func TestUnmatchedClusters(t *testing.T) {
+	conf := Config{
+		ExcludeClusters: []string{"staging", "apps/prod-kubeconfig", "self"},
+		Clusters: []ClusterConfig{
+			{Name: "apps/.*"},
+			{Name: "management"},
+			{Name: "[invalid"},
+		},
+	}
+
+	assert.DeepEqual(t, conf.UnmatchedClusters([]string{"self", "apps/prod-kubeconfig"}), []string{"staging", "management"})
+	assert.Assert(t, (&Config{}).UnmatchedClusters([]string{"self"}) == nil)
+}
+
+func TestMatchesKind(t *testing.T) {
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	core := schema.GroupVersion{Version: "v1"}
+	deployments := metav1.APIResource{Name: "deployments", SingularName: "deployment", Kind: "Deployment", ShortNames: []string{"deploy"}}
+	configMaps := metav1.APIResource{Name: "configmaps", SingularName: "configmap", Kind: "ConfigMap", ShortNames: []string{"cm"}}
+
+	tests := []struct {
+		kind     string
+		gv       schema.GroupVersion
+		resource metav1.APIResource
+		expected bool
+	}{
+		{kind: "Deployment", gv: apps, resource: deployments, expected: true},
+		{kind: "deployment", gv: apps, resource: deployments, expected: true},
+		{kind: "deployments", gv: apps, resource: deployments, expected: true},
+		{kind: "deploy", gv: apps, resource: deployments, expected: true},
+		{kind: "StatefulSet", gv: apps, resource: deployments, expected: false},
+		{kind: "deployment.apps", gv: apps, resource: deployments, expected: true},
+		{kind: "Deployment.apps", gv: apps, resource: deployments, expected: true},
+		{kind: "deployment.extensions", gv: apps, resource: deployments, expected: false},
+		{kind: "deployments.v1.apps", gv: apps, resource: deployments, expected: true},
+		{kind: "deployments.v1beta1.apps", gv: apps, resource: deployments, expected: false},
+		{kind: "cm", gv: core, resource: configMaps, expected: true},
+		{kind: "configmaps.v1", gv: core, resource: configMaps, expected: true},
+		{kind: "configmap.apps", gv: core, resource: configMaps, expected: false},
+		{kind: "deployments", gv: apps, resource: metav1.APIResource{Name: "deployments/scale", Kind: "Scale"}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind+"/"+test.gv.String()+"/"+test.resource.Name, func(t *testing.T) {
+			assert.Equal(t, MatchesKind(test.kind, test.gv, test.resource), test.expected)
+		})
+	}
+}
+
+func TestServesKind(t *testing.T) {
+	lists := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+		{GroupVersion: "invalid/group/version", APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget"}}},
+	}
+
+	assert.Assert(t, ServesKind(lists, "ConfigMap"))
+	assert.Assert(t, ServesKind(lists, "deployments.v1.apps"))
+	assert.Assert(t, !ServesKind(lists, "deployment.extensions"))
+	assert.Assert(t, !ServesKind(lists, "Widget"))
+}
@@ -0,0 +1,12 @@
+package v2
+
+import (
+	_ "embed"
+)
+
+//go:generate go run ../../../../hack/schema-gen
+
+// JSONSchema is the JSON schema of the Config, it is generated from the api types.
+//
+//go:embed config.schema.json
+var JSONSchema []byte
@@ -0,0 +1,179 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Config defines the config for gitops-zombies.
+type Config struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// AllowedExecCommands are credential plugins which kubeconfigs of remote clusters may execute with the local
+	// credentials of the user, none by default. The well-known plugins (aws, aws-iam-authenticator,
+	// gke-gcloud-auth-plugin, kubelogin) are restricted to issuing tokens.
+	AllowedExecCommands []string `json:"allowedExecCommands,omitempty"`
+	// Annotate adds the findings (severity, last modification, references, ...) as gitops-zombies.io/* annotations
+	// to the zombies printed in structured output formats. Zombies are printed as they are on the cluster otherwise.
+	Annotate *bool `json:"annotate,omitempty"`
+	// Blacklist adds resources which are considered dynamic on top of the builtin ones and the enabled presets.
+	// Dynamic resources are not reported unless includeAll is set.
+	Blacklist []GroupVersionResource `json:"blacklist,omitempty"`
+	// BlacklistPresets enables curated blacklists of common ecosystems (calico, cert-manager, cilium, istio,
+	// karpenter, velero).
+	BlacklistPresets []string `json:"blacklistPresets,omitempty"`
+	// Clusters overrides the config for clusters matching the cluster name (regexp).
+	Clusters []ClusterConfig `json:"clusters,omitempty"`
+	// ConfigMapSelector selects ConfigMaps on the flux cluster holding config fragments (key config.yaml).
+	// Fragments may only exclude resources within the namespace of their ConfigMap.
+	ConfigMapSelector string `json:"configMapSelector,omitempty"`
+	// DetectDrift reports gitops managed resources which have been modified manually (kubectl or unknown field managers).
+	DetectDrift *bool `json:"detectDrift,omitempty"`
+	// DetectHelmReleases reports releases installed by the helm cli which are not managed by a HelmRelease.
+	// Each release is reported once by its storage secret listing the resources of the release.
+	DetectHelmReleases *bool `json:"detectHelmReleases,omitempty"`
+	// DiscoverClusterAPI scans the clusters of all Cluster API Clusters (cluster.x-k8s.io) by their <name>-kubeconfig
+	// secret, including clusters no Kustomization or HelmRelease targets.
+	DiscoverClusterAPI *bool `json:"discoverClusterAPI,omitempty"`
+	// ExcludeBlacklist removes resources from the builtin blacklist, the enabled presets and the blacklist.
+	ExcludeBlacklist []GroupVersionResource `json:"excludeBlacklist,omitempty"`
+	// ExcludeClusters excludes clusters from zombie detection.
+	ExcludeClusters []string `json:"excludeClusters,omitempty"`
+	// ExcludeKinds excludes kinds from zombie detection (kind, kind.group or resource.version.group).
+	ExcludeKinds []string `json:"excludeKinds,omitempty"`
+	// ExcludeNamespaces excludes namespaces (glob or regexp) from zombie detection.
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// ExcludeResources excludes resources matching any of the rules from zombie detection.
+	ExcludeResources []ResourceRule `json:"excludeResources,omitempty"`
+	// Fail exits with an exit code > 0 if zombies are detected.
+	Fail *bool `json:"fail,omitempty"`
+	// FailOn exits with an exit code > 0 if zombies of the given or a higher severity are detected.
+	FailOn Severity `json:"failOn,omitempty"`
+	// FailThreshold exits with an exit code > 0 if more zombies of a severity than its threshold are detected.
+	FailThreshold map[Severity]int `json:"failThreshold,omitempty"`
+	// FluxSSAOwnership considers resources server-side applied by a flux controller as managed even without flux labels.
+	FluxSSAOwnership *bool `json:"fluxSSAOwnership,omitempty"`
+	// IgnorePresets ignores the objects well-known controllers create without owner references
+	// (cert-manager, istio, kubernetes-defaults).
+	IgnorePresets []string `json:"ignorePresets,omitempty"`
+	// ImpersonateServiceAccounts scans each cluster once per ServiceAccount flux impersonates while reconciling
+	// Kustomizations and HelmReleases (spec.serviceAccountName) instead of using the identity of the user.
+	ImpersonateServiceAccounts *bool `json:"impersonateServiceAccounts,omitempty"`
+	// IncludeAll includes resources which are considered dynamic resources.
+	IncludeAll *bool `json:"includeAll,omitempty"`
+	// IncludeClusterScoped scans cluster scoped resources even if zombie detection is restricted to namespaces.
+	IncludeClusterScoped *bool `json:"includeClusterScoped,omitempty"`
+	// IncludeNamespaces restricts zombie detection to namespaces (glob or regexp). Cluster scoped resources are
+	// not scanned unless includeClusterScoped is set.
+	IncludeNamespaces []string `json:"includeNamespaces,omitempty"`
+	// IncludeResources restricts zombie detection to resources matching any of the rules.
+	IncludeResources []ResourceRule `json:"includeResources,omitempty"`
+	// Kinds restricts zombie detection to kinds (kind, kind.group or resource.version.group).
+	// Kinds which are considered dynamic resources are included if they are requested explicitly.
+	Kinds []string `json:"kinds,omitempty"`
+	// LabelSelector is used while listing all apis.
+	LabelSelector string `json:"selector,omitempty"`
+	// LabelSelectors adds label selectors for specific resources on top of the selector used for all apis.
+	LabelSelectors []ResourceLabelSelector `json:"selectors,omitempty"`
+	// MinAge ignores resources younger than the given age (e.g. 24h).
+	MinAge metav1.Duration `json:"minAge,omitempty"`
+	// NoStream displays discovered resources at the end instead of live.
+	NoStream *bool `json:"noStream,omitempty"`
+	// Policy is a local directory holding rego policies and data files which are evaluated for each zombie.
+	Policy string `json:"policy,omitempty"`
+	// References annotates zombies with the resources referencing them.
+	References *bool `json:"references,omitempty"`
+	// RequestServiceAccountTokens allows requesting tokens (TokenRequest) of the ServiceAccounts configured by
+	// generic workload identity kubeConfigs on the flux cluster, which is a write. Such clusters can not be scanned otherwise.
+	RequestServiceAccountTokens *bool `json:"requestServiceAccountTokens,omitempty"`
+	// Severities assign the severity of the first matching rule to zombies instead of the builtin severity.
+	Severities []ResourceRule `json:"severities,omitempty"`
+	// SortBy sorts zombies, implies noStream. One of: age.
+	SortBy string `json:"sortBy,omitempty"`
+	// Tree displays zombies as tree grouped by namespace and owner including referenced resources, implies noStream.
+	Tree *bool `json:"tree,omitempty"`
+	// TrustedFieldManagers are field managers which are not considered manual modifications.
+	TrustedFieldManagers []string `json:"trustedFieldManagers,omitempty"`
+}
+
+// ResourceRule matches resources, all fields besides apiVersion and kind are regular expressions.
+type ResourceRule struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Cluster the rule applies to (regexp), applies to all clusters if empty.
+	Cluster string `json:"cluster,omitempty"`
+	// Expression is a CEL expression evaluated against the resource (object) which must evaluate to true.
+	// Besides the standard library object.age(), object.ownerKinds(), object.hasLabel(key) and
+	// object.hasAnnotation(key) are available.
+	Expression string `json:"expression,omitempty"`
+	// Annotations the resource must have, values are regular expressions.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels the resource must have, values are regular expressions.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Name of the resource (regexp).
+	Name string `json:"name,omitempty"`
+	// Namespace of the resource (regexp).
+	Namespace string `json:"namespace,omitempty"`
+	// Severity is assigned to matching zombies, required by severities and not supported by excludeResources.
+	Severity Severity `json:"severity,omitempty"`
+}
+
+// ClusterConfig overrides the config for clusters matching the name.
+// Lists are appended to the global ones while set values replace them.
+type ClusterConfig struct {
+	// Name of the cluster (regexp).
+	Name string `json:"name"`
+	// ExcludeNamespaces are appended to excludeNamespaces.
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// ExcludeResources are appended to excludeResources.
+	ExcludeResources []ResourceRule `json:"excludeResources,omitempty"`
+	// IncludeAll replaces includeAll if set.
+	IncludeAll *bool `json:"includeAll,omitempty"`
+	// IncludeNamespaces are appended to includeNamespaces.
+	IncludeNamespaces []string `json:"includeNamespaces,omitempty"`
+	// IncludeResources are appended to includeResources.
+	IncludeResources []ResourceRule `json:"includeResources,omitempty"`
+	// LabelSelector replaces the label selector used while listing all apis.
+	LabelSelector string `json:"selector,omitempty"`
+	// LabelSelectors are appended to selectors.
+	LabelSelectors []ResourceLabelSelector `json:"selectors,omitempty"`
+	// Severities are appended to severities.
+	Severities []ResourceRule `json:"severities,omitempty"`
+}
+
+// ResourceLabelSelector applies a label selector when listing a specific resource.
+type ResourceLabelSelector struct {
+	GroupVersionResource `json:",inline"`
+
+	// Selector is the label selector used while listing the resource.
+	Selector string `json:"selector"`
+}
+
+// GroupVersionResource references an api resource, an empty version matches all versions.
+type GroupVersionResource struct {
+	// Group of the api, empty for the core api.
+	Group string `json:"group,omitempty"`
+	// Version of the api, matches all versions if empty.
+	Version string `json:"version,omitempty"`
+	// Resource is the plural resource name (e.g. deployments).
+	Resource string `json:"resource"`
+}
+
+// Severity of a zombie.
+type Severity string
+
+const (
+	// SeverityInfo is used for zombies which are likely harmless.
+	SeverityInfo Severity = "info"
+	// SeverityWarning is used for zombies which should be reviewed.
+	SeverityWarning Severity = "warning"
+	// SeverityCritical is used for zombies which pose a risk.
+	SeverityCritical Severity = "critical"
+)
+
+// Severities are all supported severities ordered by increasing severity.
+var Severities = []Severity{SeverityInfo, SeverityWarning, SeverityCritical}
@@ -0,0 +1,72 @@
+package v2
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// fill sets every exported field reachable from v to a non zero value.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Map:
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(elem)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, elem)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+// assertNotAliased fails if any slice, map or pointer reachable from a is shared with b.
+func assertNotAliased(t *testing.T, path string, a, b reflect.Value) {
+	t.Helper()
+
+	switch a.Kind() {
+	case reflect.Pointer:
+		assert.Assert(t, a.Pointer() != b.Pointer(), "%s is aliased", path)
+		assertNotAliased(t, path, a.Elem(), b.Elem())
+	case reflect.Slice:
+		assert.Assert(t, a.Pointer() != b.Pointer(), "%s is aliased", path)
+		for i := range a.Len() {
+			assertNotAliased(t, path+"[]", a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		assert.Assert(t, a.Pointer() != b.Pointer(), "%s is aliased", path)
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if a.Type().Field(i).IsExported() {
+				assertNotAliased(t, path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+			}
+		}
+	}
+}
+
+// TestConfigDeepCopy guards against fields added without regenerating zz_generated.deepcopy.go.
+func TestConfigDeepCopy(t *testing.T) {
+	conf := &Config{}
+	fill(reflect.ValueOf(conf).Elem())
+
+	copied := conf.DeepCopy()
+	assert.DeepEqual(t, copied, conf)
+	assertNotAliased(t, "Config", reflect.ValueOf(copied).Elem(), reflect.ValueOf(conf).Elem())
+}
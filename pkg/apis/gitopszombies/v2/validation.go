@@ -0,0 +1,302 @@
+package v2
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SortByAge sorts zombies by their creation timestamp, oldest first.
+const SortByAge = "age"
+
+const (
+	// IgnorePresetKubernetesDefaults ignores objects created by the apiserver and the controller manager.
+	IgnorePresetKubernetesDefaults = "kubernetes-defaults"
+	// IgnorePresetCertManager ignores objects created by cert-manager for existing certificates.
+	IgnorePresetCertManager = "cert-manager"
+	// IgnorePresetIstio ignores objects created by istiod.
+	IgnorePresetIstio = "istio"
+)
+
+// IgnorePresets returns the names of all ignore presets.
+func IgnorePresets() []string {
+	return []string{IgnorePresetCertManager, IgnorePresetIstio, IgnorePresetKubernetesDefaults}
+}
+
+// Validate validates the config and returns all errors including their field path.
+func (c *Config) Validate() field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateBlacklist(c.Blacklist, field.NewPath("blacklist"))...)
+	errs = append(errs, validateBlacklist(c.ExcludeBlacklist, field.NewPath("excludeBlacklist"))...)
+	for i, name := range c.BlacklistPresets {
+		if _, ok := BlacklistPreset(name); !ok {
+			errs = append(errs, field.NotSupported(field.NewPath("blacklistPresets").Index(i), name, BlacklistPresets()))
+		}
+	}
+	for i, name := range c.IgnorePresets {
+		if !slices.Contains(IgnorePresets(), name) {
+			errs = append(errs, field.NotSupported(field.NewPath("ignorePresets").Index(i), name, IgnorePresets()))
+		}
+	}
+	errs = append(errs, validateLabelSelector(c.ConfigMapSelector, field.NewPath("configMapSelector"))...)
+	errs = append(errs, validateNamespacePatterns(c.ExcludeNamespaces, field.NewPath("excludeNamespaces"))...)
+	errs = append(errs, validateNamespacePatterns(c.IncludeNamespaces, field.NewPath("includeNamespaces"))...)
+	errs = append(errs, validateExclusions(c.ExcludeResources, field.NewPath("excludeResources"))...)
+	errs = append(errs, validateResourceRules(c.IncludeResources, field.NewPath("includeResources"))...)
+	errs = append(errs, validateLabelSelector(c.LabelSelector, field.NewPath("selector"))...)
+	errs = append(errs, validateLabelSelectors(c.LabelSelectors, field.NewPath("selectors"))...)
+	errs = append(errs, validateSeverityRules(c.Severities, field.NewPath("severities"))...)
+
+	if c.MinAge.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("minAge"), c.MinAge.String(), "must not be negative"))
+	}
+
+	errs = append(errs, validateSeverity(c.FailOn, field.NewPath("failOn"))...)
+	for _, severity := range slices.Sorted(maps.Keys(c.FailThreshold)) {
+		path := field.NewPath("failThreshold").Key(string(severity))
+		errs = append(errs, validateSeverity(severity, path)...)
+		if c.FailThreshold[severity] < 0 {
+			errs = append(errs, field.Invalid(path, c.FailThreshold[severity], "must not be negative"))
+		}
+	}
+
+	errs = append(errs, validateKinds(c.Kinds, field.NewPath("kinds"))...)
+	errs = append(errs, validateKinds(c.ExcludeKinds, field.NewPath("excludeKinds"))...)
+
+	if c.SortBy != "" && c.SortBy != SortByAge {
+		errs = append(errs, field.NotSupported(field.NewPath("sortBy"), c.SortBy, []string{SortByAge}))
+	}
+
+	for i, cluster := range c.Clusters {
+		path := field.NewPath("clusters").Index(i)
+		if cluster.Name == "" {
+			errs = append(errs, field.Required(path.Child("name"), "cluster name is required"))
+		} else {
+			errs = append(errs, validateRegexp(cluster.Name, path.Child("name"))...)
+		}
+
+		errs = append(errs, validateNamespacePatterns(cluster.ExcludeNamespaces, path.Child("excludeNamespaces"))...)
+		errs = append(errs, validateNamespacePatterns(cluster.IncludeNamespaces, path.Child("includeNamespaces"))...)
+		errs = append(errs, validateExclusions(cluster.ExcludeResources, path.Child("excludeResources"))...)
+		errs = append(errs, validateResourceRules(cluster.IncludeResources, path.Child("includeResources"))...)
+		errs = append(errs, validateLabelSelector(cluster.LabelSelector, path.Child("selector"))...)
+		errs = append(errs, validateLabelSelectors(cluster.LabelSelectors, path.Child("selectors"))...)
+		errs = append(errs, validateSeverityRules(cluster.Severities, path.Child("severities"))...)
+	}
+
+	return errs
+}
+
+func validateRegexp(expr string, path *field.Path) field.ErrorList {
+	if _, err := regexp.Compile(`^` + expr + `$`); err != nil {
+		return field.ErrorList{field.Invalid(path, expr, err.Error())}
+	}
+
+	return nil
+}
+
+func validateKinds(kinds []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, kind := range kinds {
+		if name, _, _ := strings.Cut(kind, "."); name == "" {
+			errs = append(errs, field.Invalid(path.Index(i), kind, "must be kind, kind.group or resource.version.group"))
+		}
+	}
+
+	return errs
+}
+
+func validateNamespacePatterns(exprs []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, expr := range exprs {
+		if _, err := regexp.Compile(`^` + NamespacePattern(expr) + `$`); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), expr, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func validateResourceRules(rules []ResourceRule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		path := path.Index(i)
+		errs = append(errs, validateRegexp(rule.Cluster, path.Child("cluster"))...)
+		errs = append(errs, validateRegexp(rule.Name, path.Child("name"))...)
+		errs = append(errs, validateRegexp(rule.Namespace, path.Child("namespace"))...)
+		errs = append(errs, validateSeverity(rule.Severity, path.Child("severity"))...)
+
+		for key, expr := range rule.Annotations {
+			errs = append(errs, validateRegexp(expr, path.Child("annotations").Key(key))...)
+		}
+
+		for key, expr := range rule.Labels {
+			errs = append(errs, validateRegexp(expr, path.Child("labels").Key(key))...)
+		}
+	}
+
+	return errs
+}
+
+// validateExclusions rejects a severity as an exclusion with a severity would report the resources it matches.
+func validateExclusions(rules []ResourceRule, path *field.Path) field.ErrorList {
+	errs := validateResourceRules(rules, path)
+	for i, rule := range rules {
+		if rule.Severity != "" {
+			errs = append(errs, field.Forbidden(path.Index(i).Child("severity"), "use severities to assign a severity"))
+		}
+	}
+
+	return errs
+}
+
+func validateSeverityRules(rules []ResourceRule, path *field.Path) field.ErrorList {
+	errs := validateResourceRules(rules, path)
+	for i, rule := range rules {
+		if rule.Severity == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("severity"), "severity is required"))
+		}
+	}
+
+	return errs
+}
+
+func validateSeverity(severity Severity, path *field.Path) field.ErrorList {
+	if severity != "" && !slices.Contains(Severities, severity) {
+		return field.ErrorList{field.NotSupported(path, severity, Severities)}
+	}
+
+	return nil
+}
+
+func validateLabelSelector(selector string, path *field.Path) field.ErrorList {
+	if _, err := labels.Parse(selector); err != nil {
+		return field.ErrorList{field.Invalid(path, selector, err.Error())}
+	}
+
+	return nil
+}
+
+func validateLabelSelectors(selectors []ResourceLabelSelector, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, selector := range selectors {
+		path := path.Index(i)
+		if selector.Resource == "" {
+			errs = append(errs, field.Required(path.Child("resource"), "resource is required"))
+		}
+
+		errs = append(errs, validateLabelSelector(selector.Selector, path.Child("selector"))...)
+	}
+
+	return errs
+}
+
+func validateBlacklist(blacklist []GroupVersionResource, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, gvr := range blacklist {
+		if gvr.Resource == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("resource"), "resource is required"))
+		}
+	}
+
+	return errs
+}
+
+// ValidateAPIs validates the kinds and resources referenced by the config against the apis served by a cluster.
+// Rules restricted to a cluster and cluster overrides are not validated as other clusters might serve different apis.
+func (c *Config) ValidateAPIs(lists []*metav1.APIResourceList) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateResourceRuleAPIs(lists, c.ExcludeResources, field.NewPath("excludeResources"))...)
+	errs = append(errs, validateResourceRuleAPIs(lists, c.IncludeResources, field.NewPath("includeResources"))...)
+	errs = append(errs, validateResourceRuleAPIs(lists, c.Severities, field.NewPath("severities"))...)
+
+	errs = append(errs, validateKindAPIs(lists, c.Kinds, field.NewPath("kinds"))...)
+	errs = append(errs, validateKindAPIs(lists, c.ExcludeKinds, field.NewPath("excludeKinds"))...)
+
+	for i, gvr := range c.Blacklist {
+		errs = append(errs, validateResourceAPI(lists, gvr, field.NewPath("blacklist").Index(i))...)
+	}
+
+	for i, selector := range c.LabelSelectors {
+		errs = append(errs, validateResourceAPI(lists, selector.GroupVersionResource, field.NewPath("selectors").Index(i))...)
+	}
+
+	return errs
+}
+
+func validateResourceRuleAPIs(lists []*metav1.APIResourceList, rules []ResourceRule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		if rule.Cluster != "" {
+			continue
+		}
+
+		path := path.Index(i)
+		served := lists
+		if rule.APIVersion != "" {
+			served = slices.DeleteFunc(slices.Clone(lists), func(list *metav1.APIResourceList) bool {
+				return list.GroupVersion != rule.APIVersion
+			})
+
+			if len(served) == 0 {
+				errs = append(errs, field.NotFound(path.Child("apiVersion"), rule.APIVersion))
+				continue
+			}
+		}
+
+		if rule.Kind != "" && !servesAPIResource(served, func(res metav1.APIResource) bool {
+			return res.Kind == rule.Kind
+		}) {
+			errs = append(errs, field.NotFound(path.Child("kind"), rule.Kind))
+		}
+	}
+
+	return errs
+}
+
+func validateKindAPIs(lists []*metav1.APIResourceList, kinds []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, kind := range kinds {
+		if !ServesKind(lists, kind) {
+			errs = append(errs, field.NotFound(path.Index(i), kind))
+		}
+	}
+
+	return errs
+}
+
+func validateResourceAPI(lists []*metav1.APIResourceList, gvr GroupVersionResource, path *field.Path) field.ErrorList {
+	served := slices.DeleteFunc(slices.Clone(lists), func(list *metav1.APIResourceList) bool {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		return err != nil || gv.Group != gvr.Group || (gvr.Version != "" && gv.Version != gvr.Version)
+	})
+
+	if !servesAPIResource(served, func(res metav1.APIResource) bool {
+		return res.Name == gvr.Resource
+	}) {
+		return field.ErrorList{field.NotFound(path.Child("resource"), gvr.Resource)}
+	}
+
+	return nil
+}
+
+func servesAPIResource(lists []*metav1.APIResourceList, match func(res metav1.APIResource) bool) bool {
+	for _, list := range lists {
+		for _, res := range list.APIResources {
+			// subresources like pods/log share the kind of their parent
+			if !strings.Contains(res.Name, "/") && match(res) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     Config
+		expected []string
+	}{
+		{
+			name: "valid config",
+			conf: Config{
+				BlacklistPresets:  []string{"cilium"},
+				ExcludeNamespaces: []string{"kube-*"},
+				ExcludeResources:  []ResourceRule{{Name: "web-.*"}},
+				FailOn:            SeverityWarning,
+				FailThreshold:     map[Severity]int{SeverityInfo: 10},
+				Kinds:             []string{"deployments.v1.apps"},
+				LabelSelector:     "app!=web",
+				MinAge:            metav1.Duration{Duration: time.Hour},
+				Severities:        []ResourceRule{{Name: "db-.*", Severity: SeverityCritical}},
+				SortBy:            SortByAge,
+				Clusters:          []ClusterConfig{{Name: "prod-.*"}},
+			},
+		},
+		{
+			name:     "blacklist without resource",
+			conf:     Config{Blacklist: []GroupVersionResource{{Group: "apps"}}},
+			expected: []string{"Required value: blacklist[0].resource"},
+		},
+		{
+			name:     "exclude blacklist without resource",
+			conf:     Config{ExcludeBlacklist: []GroupVersionResource{{}}},
+			expected: []string{"Required value: excludeBlacklist[0].resource"},
+		},
+		{
+			name:     "unknown blacklist preset",
+			conf:     Config{BlacklistPresets: []string{"unknown"}},
+			expected: []string{"Unsupported value: blacklistPresets[0]"},
+		},
+		{
+			name:     "unknown ignore preset",
+			conf:     Config{IgnorePresets: []string{IgnorePresetIstio, "linkerd"}},
+			expected: []string{"Unsupported value: ignorePresets[1]"},
+		},
+		{
+			name:     "invalid config map selector",
+			conf:     Config{ConfigMapSelector: "a=b=c"},
+			expected: []string{"Invalid value: configMapSelector"},
+		},
+		{
+			name:     "invalid namespace patterns",
+			conf:     Config{ExcludeNamespaces: []string{"("}, IncludeNamespaces: []string{"apps-[a-"}},
+			expected: []string{"Invalid value: excludeNamespaces[0]", "Invalid value: includeNamespaces[0]"},
+		},
+		{
+			name: "invalid resource rules",
+			conf: Config{
+				ExcludeResources: []ResourceRule{{
+					Cluster:     "(",
+					Name:        "(",
+					Namespace:   "(",
+					Annotations: map[string]string{"a": "("},
+					Labels:      map[string]string{"l": "("},
+				}},
+				IncludeResources: []ResourceRule{{Name: "["}},
+			},
+			expected: []string{
+				"Invalid value: excludeResources[0].cluster",
+				"Invalid value: excludeResources[0].name",
+				"Invalid value: excludeResources[0].namespace",
+				"Invalid value: excludeResources[0].annotations[a]",
+				"Invalid value: excludeResources[0].labels[l]",
+				"Invalid value: includeResources[0].name",
+			},
+		},
+		{
+			name: "severity rules",
+			conf: Config{
+				ExcludeResources: []ResourceRule{{Name: "web", Severity: SeverityInfo}},
+				Severities:       []ResourceRule{{Name: "(", Severity: "fatal"}, {Name: "db"}},
+				Clusters: []ClusterConfig{{
+					Name:             "prod",
+					ExcludeResources: []ResourceRule{{Severity: SeverityCritical}},
+					Severities:       []ResourceRule{{Name: "token"}},
+				}},
+			},
+			expected: []string{
+				"Forbidden: excludeResources[0].severity",
+				"Invalid value: severities[0].name",
+				"Unsupported value: severities[0].severity",
+				"Required value: severities[1].severity",
+				"Forbidden: clusters[0].excludeResources[0].severity",
+				"Required value: clusters[0].severities[0].severity",
+			},
+		},
+		{
+			name:     "invalid selector",
+			conf:     Config{LabelSelector: "!"},
+			expected: []string{"Invalid value: selector"},
+		},
+		{
+			name: "invalid selectors",
+			conf: Config{LabelSelectors: []ResourceLabelSelector{
+				{Selector: "app=web"},
+				{GroupVersionResource: GroupVersionResource{Resource: "pods"}, Selector: "!"},
+			}},
+			expected: []string{"Required value: selectors[0].resource", "Invalid value: selectors[1].selector"},
+		},
+		{
+			name:     "negative min age",
+			conf:     Config{MinAge: metav1.Duration{Duration: -time.Hour}},
+			expected: []string{"Invalid value: minAge"},
+		},
+		{
+			name:     "unknown fail on severity",
+			conf:     Config{FailOn: "fatal"},
+			expected: []string{"Unsupported value: failOn"},
+		},
+		{
+			name: "invalid fail thresholds",
+			conf: Config{FailThreshold: map[Severity]int{"fatal": 1, SeverityWarning: -1}},
+			expected: []string{
+				"Unsupported value: failThreshold[fatal]",
+				"Invalid value: failThreshold[warning]",
+			},
+		},
+		{
+			name:     "invalid kinds",
+			conf:     Config{Kinds: []string{".apps"}, ExcludeKinds: []string{""}},
+			expected: []string{"Invalid value: kinds[0]", "Invalid value: excludeKinds[0]"},
+		},
+		{
+			name:     "unknown sort by",
+			conf:     Config{SortBy: "name"},
+			expected: []string{"Unsupported value: sortBy"},
+		},
+		{
+			name: "invalid cluster overrides",
+			conf: Config{Clusters: []ClusterConfig{
+				{},
+				{
+					Name:              "(",
+					ExcludeNamespaces: []string{"("},
+					IncludeNamespaces: []string{"("},
+					ExcludeResources:  []ResourceRule{{Name: "("}},
+					IncludeResources:  []ResourceRule{{Name: "("}},
+					LabelSelector:     "!",
+					LabelSelectors:    []ResourceLabelSelector{{Selector: "app=web"}},
+				},
+			}},
+			expected: []string{
+				"Required value: clusters[0].name",
+				"Invalid value: clusters[1].name",
+				"Invalid value: clusters[1].excludeNamespaces[0]",
+				"Invalid value: clusters[1].includeNamespaces[0]",
+				"Invalid value: clusters[1].excludeResources[0].name",
+				"Invalid value: clusters[1].includeResources[0].name",
+				"Invalid value: clusters[1].selector",
+				"Required value: clusters[1].selectors[0].resource",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.DeepEqual(t, validationErrors(test.conf.Validate()), test.expected)
+		})
+	}
+}
+
+func validationErrors(errs field.ErrorList) []string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Type.String()+": "+err.Field)
+	}
+
+	return messages
+}
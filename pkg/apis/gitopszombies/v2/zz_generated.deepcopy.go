@@ -0,0 +1,333 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfig) DeepCopyInto(out *ClusterConfig) {
+	*out = *in
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeResources != nil {
+		in, out := &in.ExcludeResources, &out.ExcludeResources
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IncludeAll != nil {
+		in, out := &in.IncludeAll, &out.IncludeAll
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IncludeNamespaces != nil {
+		in, out := &in.IncludeNamespaces, &out.IncludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeResources != nil {
+		in, out := &in.IncludeResources, &out.IncludeResources
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LabelSelectors != nil {
+		in, out := &in.LabelSelectors, &out.LabelSelectors
+		*out = make([]ResourceLabelSelector, len(*in))
+		copy(*out, *in)
+	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfig.
+func (in *ClusterConfig) DeepCopy() *ClusterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Config) DeepCopyInto(out *Config) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.AllowedExecCommands != nil {
+		in, out := &in.AllowedExecCommands, &out.AllowedExecCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotate != nil {
+		in, out := &in.Annotate, &out.Annotate
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Blacklist != nil {
+		in, out := &in.Blacklist, &out.Blacklist
+		*out = make([]GroupVersionResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlacklistPresets != nil {
+		in, out := &in.BlacklistPresets, &out.BlacklistPresets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ClusterConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DetectDrift != nil {
+		in, out := &in.DetectDrift, &out.DetectDrift
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DetectHelmReleases != nil {
+		in, out := &in.DetectHelmReleases, &out.DetectHelmReleases
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DiscoverClusterAPI != nil {
+		in, out := &in.DiscoverClusterAPI, &out.DiscoverClusterAPI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ExcludeBlacklist != nil {
+		in, out := &in.ExcludeBlacklist, &out.ExcludeBlacklist
+		*out = make([]GroupVersionResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeClusters != nil {
+		in, out := &in.ExcludeClusters, &out.ExcludeClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeKinds != nil {
+		in, out := &in.ExcludeKinds, &out.ExcludeKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeResources != nil {
+		in, out := &in.ExcludeResources, &out.ExcludeResources
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Fail != nil {
+		in, out := &in.Fail, &out.Fail
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FailThreshold != nil {
+		in, out := &in.FailThreshold, &out.FailThreshold
+		*out = make(map[Severity]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FluxSSAOwnership != nil {
+		in, out := &in.FluxSSAOwnership, &out.FluxSSAOwnership
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IgnorePresets != nil {
+		in, out := &in.IgnorePresets, &out.IgnorePresets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImpersonateServiceAccounts != nil {
+		in, out := &in.ImpersonateServiceAccounts, &out.ImpersonateServiceAccounts
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IncludeAll != nil {
+		in, out := &in.IncludeAll, &out.IncludeAll
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IncludeClusterScoped != nil {
+		in, out := &in.IncludeClusterScoped, &out.IncludeClusterScoped
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IncludeNamespaces != nil {
+		in, out := &in.IncludeNamespaces, &out.IncludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeResources != nil {
+		in, out := &in.IncludeResources, &out.IncludeResources
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelectors != nil {
+		in, out := &in.LabelSelectors, &out.LabelSelectors
+		*out = make([]ResourceLabelSelector, len(*in))
+		copy(*out, *in)
+	}
+	out.MinAge = in.MinAge
+	if in.NoStream != nil {
+		in, out := &in.NoStream, &out.NoStream
+		*out = new(bool)
+		**out = **in
+	}
+	if in.References != nil {
+		in, out := &in.References, &out.References
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RequestServiceAccountTokens != nil {
+		in, out := &in.RequestServiceAccountTokens, &out.RequestServiceAccountTokens
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]ResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tree != nil {
+		in, out := &in.Tree, &out.Tree
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TrustedFieldManagers != nil {
+		in, out := &in.TrustedFieldManagers, &out.TrustedFieldManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
+func (in *Config) DeepCopy() *Config {
+	if in == nil {
+		return nil
+	}
+	out := new(Config)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Config) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupVersionResource) DeepCopyInto(out *GroupVersionResource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupVersionResource.
+func (in *GroupVersionResource) DeepCopy() *GroupVersionResource {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupVersionResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceLabelSelector) DeepCopyInto(out *ResourceLabelSelector) {
+	*out = *in
+	out.GroupVersionResource = in.GroupVersionResource
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceLabelSelector.
+func (in *ResourceLabelSelector) DeepCopy() *ResourceLabelSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceLabelSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRule) DeepCopyInto(out *ResourceRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRule.
+func (in *ResourceRule) DeepCopy() *ResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
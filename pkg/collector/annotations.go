@@ -0,0 +1,67 @@
+package collector
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+const (
+	annotationPrefix = "gitops-zombies.io/"
+
+	// AnnotationLastManager holds the field manager of the most recent managedFields entry.
+	AnnotationLastManager = annotationPrefix + "last-manager"
+	// AnnotationLastOperation holds the operation of the most recent managedFields entry.
+	AnnotationLastOperation = annotationPrefix + "last-operation"
+	// AnnotationLastModified holds the time of the most recent managedFields entry.
+	AnnotationLastModified = annotationPrefix + "last-modified"
+	// AnnotationManuallyModifiedBy holds the field managers which modified a resource by hand.
+	AnnotationManuallyModifiedBy = annotationPrefix + "manually-modified-by"
+	// AnnotationRootOwner references the zombie at the root of the owner chain of a resource.
+	AnnotationRootOwner = annotationPrefix + "root-owner"
+	// AnnotationDanglingOwner references an owner of a resource which does not exist anymore.
+	AnnotationDanglingOwner = annotationPrefix + "dangling-owner"
+	// AnnotationReferencedBy lists the resources referencing a resource.
+	AnnotationReferencedBy = annotationPrefix + "referenced-by"
+	// AnnotationSeverity holds the severity of a zombie.
+	AnnotationSeverity = annotationPrefix + "severity"
+	// AnnotationHelmRelease references the helm release (namespace/name) reported by its storage secret.
+	AnnotationHelmRelease = annotationPrefix + "helm-release"
+	// AnnotationHelmChart holds the chart (name-version) of a reported helm release.
+	AnnotationHelmChart = annotationPrefix + "helm-chart"
+	// AnnotationHelmReleaseResources lists the resources rendered by a reported helm release.
+	AnnotationHelmReleaseResources = annotationPrefix + "helm-release-resources"
+	// AnnotationMessages holds messages attached to a zombie by a policy.
+	AnnotationMessages = annotationPrefix + "messages"
+	// AnnotationContext holds the kubeconfig context a zombie was detected with while scanning multiple contexts.
+	AnnotationContext = annotationPrefix + "context"
+	// AnnotationCluster holds the cluster a zombie was detected on while scanning multiple contexts.
+	AnnotationCluster = annotationPrefix + "cluster"
+)
+
+// findingAnnotations are the annotations holding the findings of the analysis of a zombie.
+var findingAnnotations = []string{
+	AnnotationLastManager,
+	AnnotationLastOperation,
+	AnnotationLastModified,
+	AnnotationManuallyModifiedBy,
+	AnnotationRootOwner,
+	AnnotationDanglingOwner,
+	AnnotationReferencedBy,
+	AnnotationSeverity,
+	AnnotationHelmRelease,
+	AnnotationHelmChart,
+	AnnotationHelmReleaseResources,
+	AnnotationMessages,
+}
+
+// RemoveFindings removes the annotations holding the findings of the analysis from a resource.
+// Annotations of the resource on the cluster are kept.
+func RemoveFindings(res *unstructured.Unstructured) {
+	annotations := res.GetAnnotations()
+	for _, key := range findingAnnotations {
+		delete(annotations, key)
+	}
+
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	res.SetAnnotations(annotations)
+}
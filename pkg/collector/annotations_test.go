@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRemoveFindings(t *testing.T) {
+	res := unstructured.Unstructured{}
+	res.SetAnnotations(map[string]string{"app": "web"})
+	setAnnotation(&res, AnnotationSeverity, "warning")
+	setAnnotation(&res, AnnotationReferencedBy, "Deployment.apps/web")
+
+	RemoveFindings(&res)
+	assert.DeepEqual(t, res.GetAnnotations(), map[string]string{"app": "web"})
+
+	unannotated := unstructured.Unstructured{Object: map[string]any{}}
+	setAnnotation(&unannotated, AnnotationSeverity, "info")
+
+	RemoveFindings(&unannotated)
+	_, ok, _ := unstructured.NestedFieldNoCopy(unannotated.Object, "metadata", "annotations")
+	assert.Assert(t, !ok, "annotations added by the analysis only must be removed entirely")
+}
@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+const (
+	// expressionObject is the variable name of the resource within rule expressions.
+	expressionObject = "object"
+
+	// expressionCostLimit limits the cost of evaluating a rule expression against a single resource, the same
+	// limit the kubernetes api server applies per CEL expression. Expressions come from tenant owned config
+	// fragments and must not be able to stall a scan.
+	expressionCostLimit = 1000000
+)
+
+// errExpressionCostLimit is returned if evaluating an expression exceeds the cost limit.
+var errExpressionCostLimit = errors.New("expression exceeds the cost limit")
+
+var (
+	objectType = cel.MapType(cel.StringType, cel.DynType)
+
+	expressionEnv = sync.OnceValues(func() (*cel.Env, error) {
+		return cel.NewEnv(
+			cel.Variable(expressionObject, objectType),
+			ext.Strings(),
+			cel.Function("age",
+				cel.MemberOverload("object_age", []*cel.Type{objectType}, cel.DurationType,
+					cel.UnaryBinding(func(obj ref.Val) ref.Val {
+						return withResource(obj, func(res unstructured.Unstructured) ref.Val {
+							return types.Duration{Duration: time.Since(res.GetCreationTimestamp().Time)}
+						})
+					}),
+				),
+			),
+			cel.Function("ownerKinds",
+				cel.MemberOverload("object_owner_kinds", []*cel.Type{objectType}, cel.ListType(cel.StringType),
+					cel.UnaryBinding(func(obj ref.Val) ref.Val {
+						return withResource(obj, func(res unstructured.Unstructured) ref.Val {
+							kinds := []string{}
+							for _, owner := range res.GetOwnerReferences() {
+								kinds = append(kinds, owner.Kind)
+							}
+
+							return types.NewStringList(types.DefaultTypeAdapter, kinds)
+						})
+					}),
+				),
+			),
+			cel.Function("hasLabel",
+				cel.MemberOverload("object_has_label", []*cel.Type{objectType, cel.StringType}, cel.BoolType,
+					cel.BinaryBinding(func(obj, key ref.Val) ref.Val {
+						return withResource(obj, func(res unstructured.Unstructured) ref.Val {
+							_, ok := res.GetLabels()[string(key.(types.String))]
+							return types.Bool(ok)
+						})
+					}),
+				),
+			),
+			cel.Function("hasAnnotation",
+				cel.MemberOverload("object_has_annotation", []*cel.Type{objectType, cel.StringType}, cel.BoolType,
+					cel.BinaryBinding(func(obj, key ref.Val) ref.Val {
+						return withResource(obj, func(res unstructured.Unstructured) ref.Val {
+							_, ok := res.GetAnnotations()[string(key.(types.String))]
+							return types.Bool(ok)
+						})
+					}),
+				),
+			),
+		)
+	})
+
+	// programs caches the compiled expressions by their source.
+	programs sync.Map
+)
+
+func withResource(obj ref.Val, fn func(res unstructured.Unstructured) ref.Val) ref.Val {
+	native, err := obj.ConvertToNative(reflect.TypeFor[map[string]any]())
+	if err != nil {
+		return types.WrapErr(err)
+	}
+
+	return fn(unstructured.Unstructured{Object: native.(map[string]any)})
+}
+
+// CompileExpression compiles a rule expression and caches the program for later evaluation.
+// The expression must evaluate to a bool.
+func CompileExpression(expr string) (cel.Program, error) {
+	if program, ok := programs.Load(expr); ok {
+		return program.(cel.Program), nil
+	}
+
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to bool but evaluates to %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(expressionCostLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	programs.Store(expr, program)
+	return program, nil
+}
+
+// CompileExpressions compiles the expressions of all rules within the config and returns type-check errors
+// including their field path.
+func CompileExpressions(conf *v2.Config) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, compileRuleExpressions(conf.ExcludeResources, field.NewPath("excludeResources"))...)
+	errs = append(errs, compileRuleExpressions(conf.IncludeResources, field.NewPath("includeResources"))...)
+	errs = append(errs, compileRuleExpressions(conf.Severities, field.NewPath("severities"))...)
+
+	for i, cluster := range conf.Clusters {
+		path := field.NewPath("clusters").Index(i)
+		errs = append(errs, compileRuleExpressions(cluster.ExcludeResources, path.Child("excludeResources"))...)
+		errs = append(errs, compileRuleExpressions(cluster.IncludeResources, path.Child("includeResources"))...)
+		errs = append(errs, compileRuleExpressions(cluster.Severities, path.Child("severities"))...)
+	}
+
+	return errs
+}
+
+func compileRuleExpressions(rules []v2.ResourceRule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		if rule.Expression == "" {
+			continue
+		}
+
+		if _, err := CompileExpression(rule.Expression); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i).Child("expression"), rule.Expression, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// resourceMatchesExpression evaluates a rule expression against a resource.
+// Expressions which fail to compile or to evaluate (for example if a field does not exist) do not match.
+func resourceMatchesExpression(res unstructured.Unstructured, expr string) (bool, error) {
+	if expr == "" {
+		return true, nil
+	}
+
+	program, err := CompileExpression(expr)
+	if err != nil {
+		return false, err
+	}
+
+	return evalExpression(program, res)
+}
+
+func evalExpression(program cel.Program, res unstructured.Unstructured) (bool, error) {
+	out, details, err := program.Eval(map[string]any{expressionObject: res.Object})
+	if err != nil {
+		if cost := details.ActualCost(); cost != nil && *cost > expressionCostLimit {
+			return false, fmt.Errorf("%w of %d: %w", errExpressionCostLimit, expressionCostLimit, err)
+		}
+
+		return false, err
+	}
+
+	match, ok := out.(types.Bool)
+	return ok && bool(match), nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestResourceMatchesExpression(t *testing.T) {
+	secret := newResource("v1", "Secret", "web-tls", "1")
+	secret.SetCreationTimestamp(v1.Now())
+	secret.Object["type"] = "kubernetes.io/tls"
+	secret.SetAnnotations(map[string]string{"cert-manager.io/certificate-name": "web"})
+
+	job := newResource("batch/v1", "Job", "migrate", "2")
+	job.SetCreationTimestamp(v1.NewTime(time.Now().Add(-8 * 24 * time.Hour)))
+	job.SetLabels(map[string]string{"app": "web"})
+	job.SetOwnerReferences([]v1.OwnerReference{{Kind: "CronJob", Name: "migrate", UID: "3"}})
+
+	tests := []struct {
+		expr    string
+		secret  bool
+		job     bool
+		wantErr bool
+	}{
+		{expr: `object.type == 'kubernetes.io/tls' && object.hasAnnotation('cert-manager.io/certificate-name')`, secret: true},
+		{expr: `object.age() > duration('168h')`, job: true},
+		{expr: `'CronJob' in object.ownerKinds()`, job: true},
+		{expr: `object.hasLabel('app')`, job: true},
+		{expr: `object.metadata.name.startsWith('web')`, secret: true},
+		// evaluation errors (missing field) do not match
+		{expr: `object.type == 'Opaque'`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			match, err := resourceMatchesExpression(secret, test.expr)
+			assert.NilError(t, err)
+			assert.Equal(t, test.secret, match)
+
+			match, err = resourceMatchesExpression(job, test.expr)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.job, match)
+		})
+	}
+}
+
+func TestResourceMatchesExpressionCostLimit(t *testing.T) {
+	items := make([]any, 2000)
+	for i := range items {
+		items[i] = int64(i)
+	}
+
+	res := newResource("v1", "List", "items", "1")
+	res.Object["items"] = items
+
+	match, err := resourceMatchesExpression(res, `object.items.all(a, object.items.all(b, a >= 0))`)
+	assert.ErrorIs(t, err, errExpressionCostLimit)
+	assert.Assert(t, !match)
+
+	match, err = resourceMatchesExpression(res, `object.items.all(a, a >= 0)`)
+	assert.NilError(t, err)
+	assert.Assert(t, match)
+}
+
+func TestCompileExpressions(t *testing.T) {
+	errs := CompileExpressions(&v2.Config{
+		ExcludeResources: []v2.ResourceRule{
+			{Expression: `object.hasLabel('app')`},
+			{Expression: `object.age()`},
+		},
+		Clusters: []v2.ClusterConfig{
+			{Name: "prod", IncludeResources: []v2.ResourceRule{{Expression: `object.unknown(`}}},
+		},
+	})
+
+	var paths []string
+	for _, err := range errs {
+		assert.Equal(t, field.ErrorTypeInvalid, err.Type)
+		paths = append(paths, err.Field)
+	}
+
+	assert.DeepEqual(t, []string{"excludeResources[1].expression", "clusters[0].includeResources[0].expression"}, paths)
+}
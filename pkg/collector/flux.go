@@ -0,0 +1,148 @@
+package collector
+
+import (
+	helmapi "github.com/fluxcd/helm-controller/api/v2"
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ResourceSetKind is the kind of flux operator ResourceSets.
+	ResourceSetKind = "ResourceSet"
+	// FluxInstanceKind is the kind of flux operator FluxInstances.
+	FluxInstanceKind = "FluxInstance"
+)
+
+// FluxIndex indexes the HelmReleases and Kustomizations of a scan by namespace and name alongside the
+// inventory of each Kustomization, it is built once per scan and shared by all clusters.
+type FluxIndex struct {
+	helmReleases map[types.NamespacedName]struct{}
+	// helmStorage holds the helm storage of the HelmReleases by the cluster they target
+	helmStorage    map[string]map[types.NamespacedName]struct{}
+	kustomizations map[types.NamespacedName]map[string]struct{}
+	// inventories are the inventories of flux operator ResourceSets and FluxInstances by kind
+	inventories map[string]map[types.NamespacedName]map[string]struct{}
+}
+
+// NewFluxIndex builds a flux index from all HelmReleases and Kustomizations.
+func NewFluxIndex(helmReleases []helmapi.HelmRelease, kustomizations []ksapi.Kustomization) *FluxIndex {
+	index := &FluxIndex{
+		helmReleases:   make(map[types.NamespacedName]struct{}, len(helmReleases)),
+		helmStorage:    make(map[string]map[types.NamespacedName]struct{}),
+		kustomizations: make(map[types.NamespacedName]map[string]struct{}, len(kustomizations)),
+		inventories:    make(map[string]map[types.NamespacedName]map[string]struct{}),
+	}
+
+	for _, hr := range helmReleases {
+		index.helmReleases[types.NamespacedName{Namespace: hr.GetNamespace(), Name: hr.GetName()}] = struct{}{}
+	}
+
+	for _, ks := range kustomizations {
+		inventory := make(map[string]struct{})
+		if ks.Status.Inventory != nil {
+			for _, entry := range ks.Status.Inventory.Entries {
+				inventory[entry.ID] = struct{}{}
+			}
+		}
+
+		index.kustomizations[types.NamespacedName{Namespace: ks.GetNamespace(), Name: ks.GetName()}] = inventory
+	}
+
+	return index
+}
+
+// AddInventoryOwners indexes the status.inventory of flux operator ResourceSets and FluxInstances.
+// It must not be called once the index is shared by the clusters of a scan.
+func (i *FluxIndex) AddInventoryOwners(owners []unstructured.Unstructured) {
+	for _, owner := range owners {
+		inventory := make(map[string]struct{})
+		entries, _, _ := unstructured.NestedSlice(owner.Object, "status", "inventory", "entries")
+		for _, entry := range entries {
+			if m, ok := entry.(map[string]any); ok {
+				if id, ok := m["id"].(string); ok {
+					inventory[id] = struct{}{}
+				}
+			}
+		}
+
+		kind := owner.GetKind()
+		if i.inventories[kind] == nil {
+			i.inventories[kind] = make(map[types.NamespacedName]map[string]struct{})
+		}
+
+		i.inventories[kind][types.NamespacedName{Namespace: owner.GetNamespace(), Name: owner.GetName()}] = inventory
+	}
+}
+
+// AddHelmStorage indexes the helm storage of a HelmRelease on the cluster it targets (spec.kubeConfig).
+// It must not be called once the index is shared by the clusters of a scan.
+func (i *FluxIndex) AddHelmStorage(cluster string, hr helmapi.HelmRelease) {
+	if i.helmStorage[cluster] == nil {
+		i.helmStorage[cluster] = make(map[types.NamespacedName]struct{})
+	}
+
+	i.helmStorage[cluster][types.NamespacedName{Namespace: hr.GetStorageNamespace(), Name: hr.GetReleaseName()}] = struct{}{}
+}
+
+// HasHelmRelease returns true if the HelmRelease exists.
+func (i *FluxIndex) HasHelmRelease(name, namespace string) bool {
+	_, ok := i.helmReleases[types.NamespacedName{Namespace: namespace, Name: name}]
+	return ok
+}
+
+// ManagesHelmStorage returns true if a HelmRelease manages the helm release stored in the storage namespace of
+// the cluster.
+func (i *FluxIndex) ManagesHelmStorage(cluster, releaseName, storageNamespace string) bool {
+	_, ok := i.helmStorage[cluster][types.NamespacedName{Namespace: storageNamespace, Name: releaseName}]
+	return ok
+}
+
+// HasKustomization returns true if the Kustomization exists.
+func (i *FluxIndex) HasKustomization(name, namespace string) bool {
+	_, ok := i.kustomizations[types.NamespacedName{Namespace: namespace, Name: name}]
+	return ok
+}
+
+// InventoryContains returns true if the inventory of the Kustomization holds the object id
+// (see sigs.k8s.io/cli-utils/pkg/object.ObjMetadata).
+func (i *FluxIndex) InventoryContains(name, namespace, id string) bool {
+	_, ok := i.kustomizations[types.NamespacedName{Namespace: namespace, Name: name}][id]
+	return ok
+}
+
+// HasInventoryOwner returns true if the flux operator ResourceSet or FluxInstance exists.
+func (i *FluxIndex) HasInventoryOwner(kind, name, namespace string) bool {
+	_, ok := i.inventories[kind][types.NamespacedName{Namespace: namespace, Name: name}]
+	return ok
+}
+
+// OwnerInventoryContains returns true if the inventory of the flux operator ResourceSet or FluxInstance holds the
+// object id (see sigs.k8s.io/cli-utils/pkg/object.ObjMetadata).
+func (i *FluxIndex) OwnerInventoryContains(kind, name, namespace, id string) bool {
+	_, ok := i.inventories[kind][types.NamespacedName{Namespace: namespace, Name: name}][id]
+	return ok
+}
+
+// FluxOwner returns the kind, namespace and name of the HelmRelease, Kustomization, ResourceSet or FluxInstance
+// a resource is labeled with.
+func FluxOwner(res unstructured.Unstructured) (string, types.NamespacedName, bool) {
+	labels := res.GetLabels()
+	if name, ok := labels[fluxHelmNameLabel]; ok {
+		return helmapi.HelmReleaseKind, types.NamespacedName{Namespace: labels[fluxHelmNamespaceLabel], Name: name}, true
+	}
+
+	if name, ok := labels[fluxKustomizeNameLabel]; ok {
+		return ksapi.KustomizationKind, types.NamespacedName{Namespace: labels[fluxKustomizeNamespaceLabel], Name: name}, true
+	}
+
+	if name, ok := labels[resourceSetNameLabel]; ok {
+		return ResourceSetKind, types.NamespacedName{Namespace: labels[resourceSetNamespaceLabel], Name: name}, true
+	}
+
+	if name, ok := labels[fluxInstanceNameLabel]; ok {
+		return FluxInstanceKind, types.NamespacedName{Namespace: labels[fluxInstanceNamespaceLabel], Name: name}, true
+	}
+
+	return "", types.NamespacedName{}, false
+}
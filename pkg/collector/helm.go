@@ -0,0 +1,248 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	helmStorageType                = "helm.sh/release.v1"
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	helmStatusUninstalled          = "uninstalled"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// helmRelease is the part of a release in the helm storage needed to report it.
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Manifest  string `json:"manifest"`
+	Info      struct {
+		Status string `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"metadata"`
+	} `json:"chart"`
+
+	// resources are the references of the resources rendered by the manifest
+	resources []string
+	// secret is the storage secret of the release
+	secret unstructured.Unstructured
+}
+
+// HelmReleases indexes the releases of the helm storage of a cluster which are not managed by a HelmRelease.
+// Only the latest revision of each release is considered, older revisions are part of the release history.
+type HelmReleases struct {
+	// releases by the uid of the storage secret of their latest revision
+	releases map[types.UID]*helmRelease
+	// unmanaged holds the namespace and name of all indexed releases
+	unmanaged map[types.NamespacedName]struct{}
+}
+
+// NewHelmReleases decodes the helm storage secrets of a cluster and indexes the releases for which no HelmRelease
+// targeting the cluster exists. Storage secrets which can not be decoded are skipped.
+func NewHelmReleases(
+	cluster string,
+	resources []unstructured.Unstructured,
+	index *FluxIndex,
+	logger klog.Logger,
+) *HelmReleases {
+	latest := make(map[types.NamespacedName]unstructured.Unstructured)
+	for _, res := range resources {
+		if !isHelmStorageSecret(res) {
+			continue
+		}
+
+		key := types.NamespacedName{Namespace: res.GetNamespace(), Name: res.GetLabels()["name"]}
+		if current, ok := latest[key]; !ok || helmStorageVersion(res) > helmStorageVersion(current) {
+			latest[key] = res
+		}
+	}
+
+	releases := &HelmReleases{
+		releases:  make(map[types.UID]*helmRelease),
+		unmanaged: make(map[types.NamespacedName]struct{}),
+	}
+
+	for key, secret := range latest {
+		if index.ManagesHelmStorage(cluster, key.Name, key.Namespace) {
+			continue
+		}
+
+		release, err := decodeHelmRelease(secret)
+		if err != nil {
+			logger.V(1).
+				Info("failed to decode helm release", "name", secret.GetName(), "namespace", secret.GetNamespace(), "error", err)
+			continue
+		}
+
+		if release.Info.Status == helmStatusUninstalled {
+			continue
+		}
+
+		release.secret = secret
+		releases.releases[secret.GetUID()] = release
+		releases.unmanaged[types.NamespacedName{Namespace: release.Namespace, Name: release.Name}] = struct{}{}
+	}
+
+	return releases
+}
+
+// Resolve drops the releases whose storage secret is ignored by any of the ownership filters, their resources are
+// reported on their own instead. The filters are evaluated against a copy of each storage secret.
+func (r *HelmReleases) Resolve(filters []FilterFunc, logger klog.Logger) {
+	for uid, release := range r.releases {
+		secret := release.secret.DeepCopy()
+		if !slices.ContainsFunc(filters, func(filter FilterFunc) bool {
+			return filter(*secret, logger)
+		}) {
+			continue
+		}
+
+		logger.V(1).
+			Info("helm release storage secret is not reported, reporting the release resources instead", "name", secret.GetName(), "namespace", secret.GetNamespace())
+		delete(r.releases, uid)
+		delete(r.unmanaged, types.NamespacedName{Namespace: release.Namespace, Name: release.Name})
+	}
+}
+
+func isHelmStorageSecret(res unstructured.Unstructured) bool {
+	if res.GetKind() != "Secret" || res.GetAPIVersion() != "v1" || res.GetLabels()["owner"] != "helm" {
+		return false
+	}
+
+	secretType, _, _ := unstructured.NestedString(res.Object, "type")
+	return secretType == helmStorageType
+}
+
+func helmStorageVersion(res unstructured.Unstructured) int {
+	version, _ := strconv.Atoi(res.GetLabels()["version"])
+	return version
+}
+
+// decodeHelmRelease decodes the release of a helm storage secret,
+// the release is gzipped json encoded as base64 in addition to the base64 encoding of secret data.
+func decodeHelmRelease(secret unstructured.Unstructured) (*helmRelease, error) {
+	data, ok, _ := unstructured.NestedString(secret.Object, "data", "release")
+	if !ok {
+		return nil, errors.New("no release found in secret")
+	}
+
+	encoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(raw, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			_ = r.Close()
+		}()
+
+		if raw, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var release helmRelease
+	if err := json.Unmarshal(raw, &release); err != nil {
+		return nil, err
+	}
+
+	if release.resources, err = manifestResources(release.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return &release, nil
+}
+
+// manifestResources returns the references of all resources of a rendered manifest.
+func manifestResources(manifest string) ([]string, error) {
+	var refs []string
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifest)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var res unstructured.Unstructured
+		if err := yaml.Unmarshal(doc, &res.Object); err != nil {
+			return nil, err
+		}
+
+		if res.Object == nil || res.GetKind() == "" {
+			continue
+		}
+
+		refs = append(refs, ObjectReference(res))
+	}
+
+	slices.Sort(refs)
+	return slices.Compact(refs), nil
+}
+
+// ReportHelmReleases returns a FilterFunc which reports each release not managed by a HelmRelease once by the
+// storage secret of its latest revision. The secret is annotated with the release and its resources and the
+// release itself is stripped from it. The resources of such a release are filtered as they are listed on the
+// release instead.
+func ReportHelmReleases(releases *HelmReleases) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		if release, ok := releases.releases[res.GetUID()]; ok {
+			unstructured.RemoveNestedField(res.Object, "data")
+			setAnnotation(&res, AnnotationHelmRelease, release.Namespace+"/"+release.Name)
+			setAnnotation(&res, AnnotationHelmChart, release.Chart.Metadata.Name+"-"+release.Chart.Metadata.Version)
+			setAnnotation(&res, AnnotationHelmReleaseResources, strings.Join(release.resources, ","))
+			return false
+		}
+
+		annotations := res.GetAnnotations()
+		name, ok := annotations[helmReleaseNameAnnotation]
+		if !ok {
+			return false
+		}
+
+		namespace := annotations[helmReleaseNamespaceAnnotation]
+		if _, ok := releases.unmanaged[types.NamespacedName{Namespace: namespace, Name: name}]; ok {
+			logger.V(1).
+				Info("ignore resource part of an unmanaged helm release", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "releaseName", name, "releaseNamespace", namespace)
+			return true
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	helmapi "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+const webManifest = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: test
+`
+
+func newHelmStorageSecret(t *testing.T, release string, version int, status, uid string) unstructured.Unstructured {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"name":      release,
+		"namespace": "test",
+		"version":   version,
+		"manifest":  webManifest,
+		"info":      map[string]any{"status": status},
+		"chart":     map[string]any{"metadata": map[string]any{"name": release, "version": "1.0.0"}},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	secret := newResource("v1", "Secret", "sh.helm.release.v1."+release+".v"+strconv.Itoa(version), uid)
+	secret.SetLabels(map[string]string{
+		"owner":   "helm",
+		"name":    release,
+		"status":  status,
+		"version": strconv.Itoa(version),
+	})
+	secret.Object["type"] = helmStorageType
+	secret.Object["data"] = map[string]any{
+		"release": base64.StdEncoding.EncodeToString([]byte(encoded)),
+	}
+
+	return secret
+}
+
+func newHelmReleaseResource(name, release, uid string) unstructured.Unstructured {
+	res := newResource("apps/v1", "Deployment", name, uid)
+	res.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "Helm"})
+	res.SetAnnotations(map[string]string{
+		helmReleaseNameAnnotation:      release,
+		helmReleaseNamespaceAnnotation: "test",
+	})
+
+	return res
+}
+
+func TestReportHelmReleases(t *testing.T) {
+	resources := []unstructured.Unstructured{
+		newHelmStorageSecret(t, "web", 1, "superseded", "1"),
+		newHelmStorageSecret(t, "web", 2, "deployed", "2"),
+		newHelmStorageSecret(t, "podinfo", 1, "deployed", "3"),
+		newHelmStorageSecret(t, "old", 1, helmStatusUninstalled, "4"),
+		newHelmReleaseResource("web", "web", "5"),
+		newHelmReleaseResource("podinfo", "podinfo", "6"),
+		newResource("v1", "ConfigMap", "app", "7"),
+	}
+
+	podinfo := helmapi.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "test"}}
+	index := NewFluxIndex([]helmapi.HelmRelease{podinfo}, nil)
+	index.AddHelmStorage("self", podinfo)
+	releases := NewHelmReleases("self", resources, index, klog.Background())
+	filter := ReportHelmReleases(releases)
+
+	var ignored, reported []string
+	for _, res := range resources {
+		if filter(res, klog.Background()) {
+			ignored = append(ignored, res.GetName())
+		}
+
+		if _, ok := res.GetAnnotations()[AnnotationHelmRelease]; ok {
+			reported = append(reported, res.GetName())
+		}
+	}
+
+	assert.DeepEqual(t, ignored, []string{"web"})
+	assert.DeepEqual(t, reported, []string{"sh.helm.release.v1.web.v2"})
+
+	secret := resources[1]
+	assert.DeepEqual(t, secret.GetAnnotations(), map[string]string{
+		AnnotationHelmRelease:          "test/web",
+		AnnotationHelmChart:            "web-1.0.0",
+		AnnotationHelmReleaseResources: "Deployment.apps/test/web,Service/web",
+	})
+
+	_, ok := secret.Object["data"]
+	assert.Assert(t, !ok, "the release must be stripped from the reported storage secret")
+	assert.Assert(t, !IgnoreHelmSecret()(secret, klog.Background()))
+	assert.Assert(t, IgnoreHelmSecret()(resources[0], klog.Background()))
+
+	assert.Assert(t, !AssignSeverity()(secret, klog.Background()))
+	assert.Equal(t, Severity(secret), v2.SeverityWarning)
+}
+
+func TestNewHelmReleasesByCluster(t *testing.T) {
+	resources := []unstructured.Unstructured{
+		newHelmStorageSecret(t, "podinfo", 1, "deployed", "1"),
+	}
+
+	// a HelmRelease of the same name targeting a remote cluster does not manage the release of the flux cluster
+	podinfo := helmapi.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "test"}}
+	index := NewFluxIndex([]helmapi.HelmRelease{podinfo}, nil)
+	index.AddHelmStorage("apps/prod-kubeconfig", podinfo)
+
+	assert.Equal(t, len(NewHelmReleases("self", resources, index, klog.Background()).releases), 1)
+	assert.Equal(t, len(NewHelmReleases("apps/prod-kubeconfig", resources, index, klog.Background()).releases), 0)
+}
+
+func TestHelmReleasesResolve(t *testing.T) {
+	kept := newHelmStorageSecret(t, "web", 1, "deployed", "1")
+	managed := newHelmStorageSecret(t, "podinfo", 1, "deployed", "2")
+	labels := managed.GetLabels()
+	labels[resourceSetNameLabel] = "apps"
+	labels[resourceSetNamespaceLabel] = "test"
+	managed.SetLabels(labels)
+	resources := []unstructured.Unstructured{
+		kept,
+		managed,
+		newHelmReleaseResource("web", "web", "3"),
+		newHelmReleaseResource("podinfo", "podinfo", "4"),
+	}
+
+	index := NewFluxIndex(nil, nil)
+	index.AddInventoryOwners([]unstructured.Unstructured{
+		newInventoryOwner(ResourceSetKind, "apps", "test", "test_sh.helm.release.v1.podinfo.v1__Secret"),
+	})
+
+	releases := NewHelmReleases("self", resources, index, klog.Background())
+	assert.Equal(t, len(releases.releases), 2)
+
+	filter := ReportHelmReleases(releases)
+	releases.Resolve([]FilterFunc{IgnoreIfResourceSetFound(index)}, klog.Background())
+
+	// the resources of the release whose storage secret is managed are reported on their own
+	assert.Equal(t, len(releases.releases), 1)
+	assert.Assert(t, filter(resources[2], klog.Background()))
+	assert.Assert(t, !filter(resources[3], klog.Background()))
+
+	// the storage secrets are evaluated as copies
+	_, ok := resources[0].Object["data"]
+	assert.Assert(t, ok)
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+// LastModification returns the most recent managedFields entry of a resource.
+// The second return value is false if the resource does not carry any timestamped managedFields entry.
+func LastModification(res unstructured.Unstructured) (metav1.ManagedFieldsEntry, bool) {
+	var (
+		last  metav1.ManagedFieldsEntry
+		found bool
+	)
+
+	for _, entry := range res.GetManagedFields() {
+		if entry.Time == nil {
+			continue
+		}
+
+		if !found || entry.Time.After(last.Time.Time) {
+			last = entry
+			found = true
+		}
+	}
+
+	return last, found
+}
+
+// AnnotateLastModification adds the most recent managedFields entry of a resource as annotations.
+func AnnotateLastModification(res *unstructured.Unstructured) {
+	entry, ok := LastModification(*res)
+	if !ok {
+		return
+	}
+
+	setAnnotation(res, AnnotationLastManager, entry.Manager)
+	setAnnotation(res, AnnotationLastOperation, string(entry.Operation))
+	setAnnotation(res, AnnotationLastModified, entry.Time.UTC().Format(time.RFC3339))
+}
+
+var (
+	// manualFieldManagers are field managers used by kubectl when a resource is changed by hand.
+	manualFieldManagers = []string{
+		"kubectl",
+		"kubectl-annotate",
+		"kubectl-client-side-apply",
+		"kubectl-create",
+		"kubectl-edit",
+		"kubectl-label",
+		"kubectl-patch",
+		"kubectl-replace",
+		"kubectl-rollout",
+		"kubectl-scale",
+		"kubectl-set",
+	}
+
+	// fluxFieldManagers are field managers used by the flux controllers applying resources.
+	fluxFieldManagers = []string{
+		"kustomize-controller",
+		"helm-controller",
+		"flux-operator",
+	}
+
+	// trustedFieldManagers are field managers which are expected to modify gitops managed resources.
+	trustedFieldManagers = []string{
+		"before-first-apply",
+		"kube-apiserver",
+		"kube-controller-manager",
+		"kube-scheduler",
+		"kubelet",
+		"kustomize-controller",
+		"helm-controller",
+		"source-controller",
+		"notification-controller",
+		"image-reflector-controller",
+		"image-automation-controller",
+		"flux-operator",
+	}
+)
+
+// ManualModifications returns the field managers which modified a resource by hand.
+// Field managers listed in trusted (on top of the kubernetes and flux builtin ones) are not considered manual while
+// kubectl and any other unknown field manager is.
+// Entries for subresources like status or scale are not considered.
+func ManualModifications(res unstructured.Unstructured, trusted []string) []string {
+	var managers []string
+	for _, entry := range res.GetManagedFields() {
+		if entry.Subresource != "" || slices.Contains(managers, entry.Manager) {
+			continue
+		}
+
+		if !slices.Contains(manualFieldManagers, entry.Manager) &&
+			(slices.Contains(trustedFieldManagers, entry.Manager) || slices.Contains(trusted, entry.Manager)) {
+			continue
+		}
+
+		managers = append(managers, entry.Manager)
+	}
+
+	return managers
+}
+
+// AnnotateManualModifications adds the field managers which modified a resource by hand as annotation.
+func AnnotateManualModifications(res *unstructured.Unstructured, trusted []string) {
+	managers := ManualModifications(*res, trusted)
+	if len(managers) == 0 {
+		return
+	}
+
+	setAnnotation(res, AnnotationManuallyModifiedBy, strings.Join(managers, ","))
+}
+
+// IgnoreIfAppliedByFlux returns a FilterFunc which filters resources server-side applied by a flux controller.
+// This is used as evidence for gitops ownership even if a resource does not carry any flux labels.
+func IgnoreIfAppliedByFlux() FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		for _, entry := range res.GetManagedFields() {
+			if entry.Operation == metav1.ManagedFieldsOperationApply && slices.Contains(fluxFieldManagers, entry.Manager) {
+				logger.V(1).
+					Info("ignore resource applied by flux", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "fieldManager", entry.Manager)
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ReportManuallyModified wraps a FilterFunc which filters gitops managed resources.
+// Resources filtered by it are reported nonetheless if they have been modified by hand afterwards.
+func ReportManuallyModified(trusted []string, filter FilterFunc) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		if !filter(res, logger) {
+			return false
+		}
+
+		if managers := ManualModifications(res, trusted); len(managers) > 0 {
+			logger.V(1).
+				Info("report managed resource which was modified manually", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "fieldManagers", managers)
+			AnnotateManualModifications(&res, trusted)
+			return false
+		}
+
+		return true
+	}
+}
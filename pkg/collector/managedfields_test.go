@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLastModification(t *testing.T) {
+	created := v1.NewTime(time.Now().Add(-48 * time.Hour))
+	edited := v1.NewTime(time.Now().Add(-time.Hour))
+
+	res := unstructured.Unstructured{}
+	res.SetName("resource")
+	res.SetManagedFields([]v1.ManagedFieldsEntry{
+		{Manager: "kustomize-controller", Operation: v1.ManagedFieldsOperationApply, Time: &created},
+		{Manager: "kubectl-edit", Operation: v1.ManagedFieldsOperationUpdate, Time: &edited},
+		{Manager: "no-timestamp", Operation: v1.ManagedFieldsOperationUpdate},
+	})
+
+	entry, ok := LastModification(res)
+	assert.Assert(t, ok)
+	assert.Equal(t, "kubectl-edit", entry.Manager)
+
+	AnnotateLastModification(&res)
+	assert.Equal(t, "kubectl-edit", res.GetAnnotations()[AnnotationLastManager])
+	assert.Equal(t, "Update", res.GetAnnotations()[AnnotationLastOperation])
+
+	_, ok = LastModification(unstructured.Unstructured{})
+	assert.Assert(t, !ok)
+}
+
+func TestManualModifications(t *testing.T) {
+	res := unstructured.Unstructured{}
+	res.SetName("resource")
+	res.SetManagedFields([]v1.ManagedFieldsEntry{
+		{Manager: "kustomize-controller", Operation: v1.ManagedFieldsOperationApply},
+		{Manager: "kubectl-edit", Operation: v1.ManagedFieldsOperationUpdate},
+		{Manager: "kubectl-edit", Operation: v1.ManagedFieldsOperationUpdate},
+		{Manager: "kube-controller-manager", Operation: v1.ManagedFieldsOperationUpdate},
+		{Manager: "my-operator", Operation: v1.ManagedFieldsOperationUpdate},
+		{Manager: "my-script", Operation: v1.ManagedFieldsOperationUpdate},
+		{Manager: "kubectl-scale", Operation: v1.ManagedFieldsOperationUpdate, Subresource: "scale"},
+	})
+
+	assert.DeepEqual(t, []string{"kubectl-edit", "my-script"}, ManualModifications(res, []string{"my-operator"}))
+
+	AnnotateManualModifications(&res, []string{"my-operator"})
+	assert.Equal(t, "kubectl-edit,my-script", res.GetAnnotations()[AnnotationManuallyModifiedBy])
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// OwnerGraph indexes all resources of a cluster by uid to resolve owner reference chains.
+type OwnerGraph struct {
+	resources   map[types.UID]unstructured.Unstructured
+	listedKinds []schema.GroupKind
+	zombies     map[types.UID]struct{}
+	mu          sync.RWMutex
+}
+
+// NewOwnerGraph builds an owner graph from all resources listed on a cluster.
+// Owner references pointing to kinds which are not part of listedKinds are never considered dangling as
+// the owner might just not have been listed.
+func NewOwnerGraph(resources []unstructured.Unstructured, listedKinds []schema.GroupKind) *OwnerGraph {
+	graph := &OwnerGraph{
+		resources:   make(map[types.UID]unstructured.Unstructured, len(resources)),
+		listedKinds: listedKinds,
+		zombies:     make(map[types.UID]struct{}),
+	}
+
+	for _, res := range resources {
+		if uid := res.GetUID(); uid != "" {
+			graph.resources[uid] = res
+		}
+	}
+
+	return graph
+}
+
+// MarkZombie records a resource as zombie so resources owned by it are reported as well.
+func (g *OwnerGraph) MarkZombie(res unstructured.Unstructured) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.zombies[res.GetUID()] = struct{}{}
+}
+
+// IsZombie returns true if the resource was marked as zombie.
+func (g *OwnerGraph) IsZombie(res unstructured.Unstructured) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.zombies[res.GetUID()]
+	return ok
+}
+
+// Root walks the owner references of a resource up to its root owner.
+// If an owner within the chain does not exist the dangling owner reference is returned instead.
+// Both are nil if the chain can not be resolved because an owner was not listed.
+func (g *OwnerGraph) Root(res unstructured.Unstructured) (*unstructured.Unstructured, *metav1.OwnerReference) {
+	var root *unstructured.Unstructured
+	visited := map[types.UID]struct{}{res.GetUID(): {}}
+
+	for {
+		ref := ControllerOf(res.GetOwnerReferences())
+		if ref == nil {
+			return root, nil
+		}
+
+		owner, ok := g.resources[ref.UID]
+		if !ok {
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err == nil && slices.Contains(g.listedKinds, gv.WithKind(ref.Kind).GroupKind()) {
+				return nil, ref
+			}
+
+			return nil, nil
+		}
+
+		if _, ok := visited[owner.GetUID()]; ok {
+			return root, nil
+		}
+
+		visited[owner.GetUID()] = struct{}{}
+		root = &owner
+		res = owner
+	}
+}
+
+// ControllerOf returns the managing controller reference or the first owner reference if there is none.
+func ControllerOf(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+
+	return &refs[0]
+}
+
+// IgnoreOwnedByManagedResource returns a FilterFunc which filters resources owned by parents which are not zombies.
+// Owned resources are reported if the root of their owner chain is a zombie or if an owner does not exist anymore.
+// Root owners need to be discovered and marked using OwnerGraph.MarkZombie before owned resources are discovered.
+func IgnoreOwnedByManagedResource(graph *OwnerGraph) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		if len(res.GetOwnerReferences()) == 0 {
+			return false
+		}
+
+		root, dangling := graph.Root(res)
+		switch {
+		case dangling != nil:
+			logger.V(1).
+				Info("report resource with dangling owner reference", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "ownerKind", dangling.Kind, "ownerName", dangling.Name, "ownerUID", dangling.UID)
+			setAnnotation(&res, AnnotationDanglingOwner, fmt.Sprintf("%s/%s (%s)", dangling.Kind, dangling.Name, dangling.UID))
+			return false
+		case root != nil && graph.IsZombie(*root):
+			logger.V(1).
+				Info("report resource owned by zombie", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "rootName", root.GetName(), "rootNamespace", root.GetNamespace(), "rootApiVersion", root.GetAPIVersion())
+			setAnnotation(&res, AnnotationRootOwner, ObjectReference(*root))
+			return false
+		}
+
+		logger.V(1).
+			Info("ignore resource owned by parent", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion())
+		return true
+	}
+}
+
+// ObjectReference returns a human readable reference to a resource in the form Kind[.group]/[namespace/]name.
+func ObjectReference(res unstructured.Unstructured) string {
+	ref := res.GroupVersionKind().GroupKind().String() + "/"
+	if res.GetNamespace() != "" {
+		ref += res.GetNamespace() + "/"
+	}
+
+	return ref + res.GetName()
+}
+
+func setAnnotation(res *unstructured.Unstructured, key, value string) {
+	annotations := res.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[key] = value
+	res.SetAnnotations(annotations)
+}
@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+func newOwnedResource(gvk schema.GroupVersionKind, name string, uid types.UID, owner *unstructured.Unstructured) unstructured.Unstructured {
+	res := unstructured.Unstructured{}
+	res.SetGroupVersionKind(gvk)
+	res.SetNamespace("test")
+	res.SetName(name)
+	res.SetUID(uid)
+
+	if owner != nil {
+		controller := true
+		res.SetOwnerReferences([]v1.OwnerReference{
+			{
+				APIVersion: owner.GetAPIVersion(),
+				Kind:       owner.GetKind(),
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: &controller,
+			},
+		})
+	}
+
+	return res
+}
+
+func TestOwnerGraph(t *testing.T) {
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	replicaSetGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	zombie := newOwnedResource(deploymentGVK, "zombie", "1", nil)
+	zombieReplicaSet := newOwnedResource(replicaSetGVK, "zombie-rs", "2", &zombie)
+	zombiePod := newOwnedResource(podGVK, "zombie-pod", "3", &zombieReplicaSet)
+
+	managed := newOwnedResource(deploymentGVK, "managed", "4", nil)
+	managedReplicaSet := newOwnedResource(replicaSetGVK, "managed-rs", "5", &managed)
+
+	deleted := newOwnedResource(deploymentGVK, "deleted", "6", nil)
+	danglingReplicaSet := newOwnedResource(replicaSetGVK, "dangling-rs", "7", &deleted)
+
+	node := unstructured.Unstructured{}
+	node.SetAPIVersion("v1")
+	node.SetKind("Node")
+	node.SetName("node")
+	node.SetUID("8")
+	mirrorPod := newOwnedResource(podGVK, "mirror-pod", "9", &node)
+
+	graph := NewOwnerGraph(
+		[]unstructured.Unstructured{zombie, zombieReplicaSet, zombiePod, managed, managedReplicaSet, danglingReplicaSet, mirrorPod},
+		[]schema.GroupKind{deploymentGVK.GroupKind(), replicaSetGVK.GroupKind(), podGVK.GroupKind()},
+	)
+	graph.MarkZombie(zombie)
+
+	root, dangling := graph.Root(zombiePod)
+	require.NotNil(t, root)
+	assert.Equal(t, "zombie", root.GetName())
+	assert.Assert(t, dangling == nil)
+
+	root, dangling = graph.Root(danglingReplicaSet)
+	assert.Assert(t, root == nil)
+	require.NotNil(t, dangling)
+	assert.Equal(t, "deleted", dangling.Name)
+
+	root, dangling = graph.Root(mirrorPod)
+	assert.Assert(t, root == nil)
+	assert.Assert(t, dangling == nil)
+
+	list := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{zombieReplicaSet, zombiePod, managedReplicaSet, danglingReplicaSet, mirrorPod},
+	}
+
+	ch := make(chan unstructured.Unstructured, len(list.Items))
+	discovery := NewDiscovery(klog.NewKlogr(), IgnoreOwnedByManagedResource(graph))
+	err := discovery.Discover(t.Context(), list, ch)
+	require.NoError(t, err)
+	close(ch)
+
+	var reported []string
+	for res := range ch {
+		reported = append(reported, res.GetName())
+	}
+
+	assert.DeepEqual(t, []string{"zombie-rs", "zombie-pod", "dangling-rs"}, reported)
+	assert.Equal(t, "Deployment.apps/test/zombie", zombiePod.GetAnnotations()[AnnotationRootOwner])
+	assert.Equal(t, "Deployment/deleted (6)", danglingReplicaSet.GetAnnotations()[AnnotationDanglingOwner])
+}
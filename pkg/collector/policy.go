@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	helmapi "github.com/fluxcd/helm-controller/api/v2"
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+// policyQuery is the document holding the policy decision for a zombie.
+const policyQuery = "data.gitopszombies"
+
+// Policy evaluates zombies against rego policies.
+type Policy struct {
+	query rego.PreparedEvalQuery
+}
+
+// PolicyInput is the input document of a policy evaluation.
+type PolicyInput struct {
+	Cluster string         `json:"cluster"`
+	Object  map[string]any `json:"object"`
+	Owner   *PolicyOwner   `json:"owner,omitempty"`
+}
+
+// PolicyOwner is the flux owner a resource is labeled with.
+type PolicyOwner struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Found is true if the owner exists on the cluster.
+	Found bool `json:"found"`
+}
+
+// PolicyDecision is the decision of a policy for a zombie.
+type PolicyDecision struct {
+	Ignore   bool        `json:"ignore"`
+	Severity v2.Severity `json:"severity"`
+	Messages []string    `json:"messages"`
+}
+
+// LoadPolicy compiles all rego policies and data files within a local bundle directory.
+// Policies are expected within the package gitopszombies and may define the rules ignore, severity and messages.
+func LoadPolicy(ctx context.Context, dir string) (*Policy, error) {
+	query, err := rego.New(
+		rego.Query(policyQuery),
+		rego.Load([]string{dir}, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy %s: %w", dir, err)
+	}
+
+	return &Policy{query: query}, nil
+}
+
+// Evaluate evaluates the policy for a zombie.
+func (p *Policy) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	var decision PolicyDecision
+	rs, err := p.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return decision, err
+	}
+
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return decision, nil
+	}
+
+	b, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return decision, err
+	}
+
+	if err := json.Unmarshal(b, &decision); err != nil {
+		return decision, fmt.Errorf("invalid policy decision: %w", err)
+	}
+
+	if decision.Severity != "" && !slices.Contains(v2.Severities, decision.Severity) {
+		return decision, fmt.Errorf("invalid policy decision: unsupported severity %q", decision.Severity)
+	}
+
+	return decision, nil
+}
+
+// IgnoreByPolicy returns a FilterFunc which evaluates resources against the policy and filters the ones ignored by it.
+// The severity and messages decided by the policy are attached as annotations.
+// Resources are not filtered if the policy fails to evaluate.
+func IgnoreByPolicy(
+	policy *Policy,
+	cluster string,
+	index *FluxIndex,
+) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		input := PolicyInput{
+			Cluster: cluster,
+			Object:  res.Object,
+			Owner:   policyOwner(res, index),
+		}
+
+		decision, err := policy.Evaluate(context.TODO(), input)
+		if err != nil {
+			logger.Error(err, "failed to evaluate policy", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion())
+			return false
+		}
+
+		if decision.Ignore {
+			logger.V(1).
+				Info("ignore resource by policy", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion())
+			return true
+		}
+
+		if decision.Severity != "" {
+			setAnnotation(&res, AnnotationSeverity, string(decision.Severity))
+		}
+
+		if len(decision.Messages) > 0 {
+			setAnnotation(&res, AnnotationMessages, strings.Join(decision.Messages, "; "))
+		}
+
+		return false
+	}
+}
+
+func policyOwner(res unstructured.Unstructured, index *FluxIndex) *PolicyOwner {
+	kind, owner, ok := FluxOwner(res)
+	if !ok {
+		return nil
+	}
+
+	var found bool
+	switch kind {
+	case helmapi.HelmReleaseKind:
+		found = index.HasHelmRelease(owner.Name, owner.Namespace)
+	case ksapi.KustomizationKind:
+		found = index.HasKustomization(owner.Name, owner.Namespace)
+	default:
+		found = index.HasInventoryOwner(kind, owner.Name, owner.Namespace)
+	}
+
+	return &PolicyOwner{
+		Kind:      kind,
+		Name:      owner.Name,
+		Namespace: owner.Namespace,
+		Found:     found,
+	}
+}
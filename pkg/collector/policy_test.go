@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	"k8s.io/klog/v2"
+)
+
+const testPolicy = `package gitopszombies
+
+ignore if input.object.metadata.name == "allowed"
+
+severity := "critical" if {
+	input.object.kind == "ClusterRoleBinding"
+} else := "info"
+
+messages contains msg if {
+	input.owner.kind == "Kustomization"
+	not input.owner.found
+	msg := sprintf("kustomization %s/%s does not exist anymore", [input.owner.namespace, input.owner.name])
+}
+
+messages contains msg if {
+	msg := sprintf("found on cluster %s", [input.cluster])
+}
+`
+
+func TestIgnoreByPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte(testPolicy), 0o600))
+
+	policy, err := LoadPolicy(t.Context(), dir)
+	require.NoError(t, err)
+
+	allowed := newResource("v1", "ConfigMap", "allowed", "1")
+	binding := newResource("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "admin", "2")
+	orphan := newResource("v1", "ConfigMap", "orphan", "3")
+	orphan.SetLabels(map[string]string{
+		fluxKustomizeNameLabel:      "apps",
+		fluxKustomizeNamespaceLabel: "flux-system",
+	})
+
+	filter := IgnoreByPolicy(policy, "self", NewFluxIndex(nil, nil))
+	assert.Equal(t, true, filter(allowed, klog.NewKlogr()))
+	assert.Equal(t, false, filter(binding, klog.NewKlogr()))
+	assert.Equal(t, false, filter(orphan, klog.NewKlogr()))
+
+	assert.DeepEqual(t, map[string]string{
+		AnnotationSeverity: "critical",
+		AnnotationMessages: "found on cluster self",
+	}, binding.GetAnnotations())
+	assert.DeepEqual(t, map[string]string{
+		AnnotationSeverity: "info",
+		AnnotationMessages: "found on cluster self; kustomization flux-system/apps does not exist anymore",
+	}, orphan.GetAnnotations())
+}
+
+func TestLoadPolicyFailsOnInvalidPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package gitopszombies\nignore if {"), 0o600))
+
+	_, err := LoadPolicy(t.Context(), dir)
+	require.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+// certificateKind is the kind of cert-manager certificates.
+var certificateKind = schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"}
+
+// wellKnownObject describes objects a controller creates without owner references.
+// Empty fields match all objects.
+type wellKnownObject struct {
+	kind       schema.GroupKind
+	namespace  string
+	name       string
+	annotation string
+	// referenced requires the object of this kind named by the annotation value to exist in the same namespace.
+	referenced schema.GroupKind
+}
+
+// objectKey identifies an object of a kind within a cluster.
+type objectKey struct {
+	kind      schema.GroupKind
+	namespace string
+	name      string
+}
+
+func (o wellKnownObject) matches(res unstructured.Unstructured) bool {
+	if res.GroupVersionKind().GroupKind() != o.kind {
+		return false
+	}
+
+	if o.namespace != "" && res.GetNamespace() != o.namespace {
+		return false
+	}
+
+	if o.name != "" && res.GetName() != o.name {
+		return false
+	}
+
+	if o.annotation != "" {
+		if _, ok := res.GetAnnotations()[o.annotation]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ignorePresets are the well-known objects of each ignore preset.
+var ignorePresets = map[string][]wellKnownObject{
+	v2.IgnorePresetKubernetesDefaults: {
+		// the builtin namespaces and the kubernetes service are created by the apiserver
+		{kind: schema.GroupKind{Kind: "Namespace"}, name: "default"},
+		{kind: schema.GroupKind{Kind: "Namespace"}, name: "kube-node-lease"},
+		{kind: schema.GroupKind{Kind: "Namespace"}, name: "kube-public"},
+		{kind: schema.GroupKind{Kind: "Namespace"}, name: "kube-system"},
+		{kind: schema.GroupKind{Kind: "Service"}, namespace: "default", name: "kubernetes"},
+		// the root ca is published into each namespace by the controller manager
+		{kind: schema.GroupKind{Kind: "ConfigMap"}, name: "kube-root-ca.crt"},
+		// each namespace gets a default service account from the controller manager
+		{kind: schema.GroupKind{Kind: "ServiceAccount"}, name: "default"},
+		// client ca and token tracking of the apiserver
+		{kind: schema.GroupKind{Kind: "ConfigMap"}, namespace: "kube-system", name: "extension-apiserver-authentication"},
+		{
+			kind:      schema.GroupKind{Kind: "ConfigMap"},
+			namespace: "kube-system",
+			name:      "kube-apiserver-legacy-service-account-token-tracking",
+		},
+	},
+	v2.IgnorePresetCertManager: {
+		// requests are created for each issuance of a certificate
+		{
+			kind:       schema.GroupKind{Group: "cert-manager.io", Kind: "CertificateRequest"},
+			annotation: "cert-manager.io/certificate-name",
+			referenced: certificateKind,
+		},
+		// secrets issued for certificates, the secret of a deleted certificate is left behind
+		{kind: schema.GroupKind{Kind: "Secret"}, annotation: "cert-manager.io/certificate-name", referenced: certificateKind},
+		// ca of the webhook generated by cert-manager itself
+		{kind: schema.GroupKind{Kind: "Secret"}, name: "cert-manager-webhook-ca"},
+	},
+	v2.IgnorePresetIstio: {
+		// the root certificate is published into each namespace by istiod
+		{kind: schema.GroupKind{Kind: "ConfigMap"}, name: "istio-ca-root-cert"},
+		// self signed ca and webhook certificates generated by istiod
+		{kind: schema.GroupKind{Kind: "Secret"}, name: "istio-ca-secret"},
+		{kind: schema.GroupKind{Kind: "Secret"}, name: "istiod-tls"},
+	},
+}
+
+// IgnoreWellKnownObjects returns a FilterFunc which filters the objects well-known controllers create without
+// owner references according to the enabled presets.
+// Objects which require a referenced object (like the secret of a certificate) are only filtered if the referenced
+// object is part of the resources. If its kind is not part of listedKinds it is assumed to exist as it might just
+// not have been listed.
+func IgnoreWellKnownObjects(
+	presets []string,
+	resources []unstructured.Unstructured,
+	listedKinds []schema.GroupKind,
+) (FilterFunc, error) {
+	var objects []wellKnownObject
+	referenced := make(map[schema.GroupKind]struct{})
+	for _, name := range presets {
+		preset, ok := ignorePresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown ignore preset %q", name)
+		}
+
+		objects = append(objects, preset...)
+		for _, o := range preset {
+			if !o.referenced.Empty() {
+				referenced[o.referenced] = struct{}{}
+			}
+		}
+	}
+
+	existing := make(map[objectKey]struct{})
+	for _, res := range resources {
+		kind := res.GroupVersionKind().GroupKind()
+		if _, ok := referenced[kind]; ok {
+			existing[objectKey{kind: kind, namespace: res.GetNamespace(), name: res.GetName()}] = struct{}{}
+		}
+	}
+
+	exists := func(o wellKnownObject, res unstructured.Unstructured) bool {
+		if o.referenced.Empty() || !slices.Contains(listedKinds, o.referenced) {
+			return true
+		}
+
+		key := objectKey{kind: o.referenced, namespace: res.GetNamespace(), name: res.GetAnnotations()[o.annotation]}
+		_, ok := existing[key]
+		return ok
+	}
+
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		if slices.ContainsFunc(objects, func(o wellKnownObject) bool {
+			return o.matches(res) && exists(o, res)
+		}) {
+			logger.V(1).
+				Info("ignore well-known object", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion())
+			return true
+		}
+
+		return false
+	}, nil
+}
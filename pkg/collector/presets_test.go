@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestIgnoreWellKnownObjects(t *testing.T) {
+	request := newResource("cert-manager.io/v1", "CertificateRequest", "web-1", "4")
+	request.SetAnnotations(map[string]string{"cert-manager.io/certificate-name": "web"})
+	authentication := newResource("v1", "ConfigMap", "extension-apiserver-authentication", "5")
+	authentication.SetNamespace("kube-system")
+	secret := newResource("v1", "Secret", "web-tls", "8")
+	secret.SetAnnotations(map[string]string{"cert-manager.io/certificate-name": "web"})
+	orphaned := newResource("v1", "Secret", "api-tls", "9")
+	orphaned.SetAnnotations(map[string]string{"cert-manager.io/certificate-name": "api"})
+	certificate := newResource("cert-manager.io/v1", "Certificate", "web", "10")
+	otherNamespace := newResource("cert-manager.io/v1", "Certificate", "api", "11")
+	otherNamespace.SetNamespace("other")
+
+	resources := []unstructured.Unstructured{
+		newResource("v1", "ConfigMap", "kube-root-ca.crt", "1"),
+		newResource("v1", "ConfigMap", "istio-ca-root-cert", "2"),
+		newResource("v1", "ServiceAccount", "default", "3"),
+		request,
+		authentication,
+		newResource("v1", "ConfigMap", "extension-apiserver-authentication", "6"),
+		newResource("v1", "ServiceAccount", "app", "7"),
+		secret,
+		orphaned,
+		certificate,
+		otherNamespace,
+	}
+
+	certificates := schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"}
+
+	tests := []struct {
+		name        string
+		presets     []string
+		listedKinds []schema.GroupKind
+		ignored     []string
+	}{
+		{
+			name: "no presets",
+		},
+		{
+			name:    "kubernetes defaults",
+			presets: []string{v2.IgnorePresetKubernetesDefaults},
+			ignored: []string{"kube-root-ca.crt", "default", "extension-apiserver-authentication"},
+		},
+		{
+			name:        "certificates of existing certificates only",
+			presets:     []string{v2.IgnorePresetIstio, v2.IgnorePresetCertManager},
+			listedKinds: []schema.GroupKind{certificates},
+			ignored:     []string{"istio-ca-root-cert", "web-1", "web-tls"},
+		},
+		{
+			name:    "certificates are assumed to exist if they were not listed",
+			presets: []string{v2.IgnorePresetCertManager},
+			ignored: []string{"web-1", "web-tls", "api-tls"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := IgnoreWellKnownObjects(test.presets, resources, test.listedKinds)
+			require.NoError(t, err)
+
+			var ignored []string
+			for _, res := range resources {
+				if filter(res, klog.Background()) {
+					ignored = append(ignored, res.GetName())
+				}
+			}
+
+			assert.DeepEqual(t, ignored, test.ignored)
+		})
+	}
+}
+
+func TestIgnorePresets(t *testing.T) {
+	for _, name := range v2.IgnorePresets() {
+		assert.Assert(t, len(ignorePresets[name]) > 0, name)
+	}
+
+	assert.Equal(t, len(ignorePresets), len(v2.IgnorePresets()))
+
+	_, err := IgnoreWellKnownObjects([]string{"linkerd"}, nil, nil)
+	require.Error(t, err)
+}
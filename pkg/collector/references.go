@@ -0,0 +1,347 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	configMapKind             = schema.GroupKind{Kind: "ConfigMap"}
+	secretKind                = schema.GroupKind{Kind: "Secret"}
+	serviceAccountKind        = schema.GroupKind{Kind: "ServiceAccount"}
+	persistentVolumeClaimKind = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+	inputProviderKind         = schema.GroupKind{Group: "fluxcd.controlplane.io", Kind: "ResourceSetInputProvider"}
+)
+
+// podSpecPaths are the paths to the pod spec (template) of workload kinds.
+var podSpecPaths = map[schema.GroupKind][]string{
+	{Kind: "Pod"}:                        {"spec"},
+	{Kind: "ReplicationController"}:      {"spec", "template", "spec"},
+	{Group: "apps", Kind: "Deployment"}:  {"spec", "template", "spec"},
+	{Group: "apps", Kind: "ReplicaSet"}:  {"spec", "template", "spec"},
+	{Group: "apps", Kind: "StatefulSet"}: {"spec", "template", "spec"},
+	{Group: "apps", Kind: "DaemonSet"}:   {"spec", "template", "spec"},
+	{Group: "batch", Kind: "Job"}:        {"spec", "template", "spec"},
+	{Group: "batch", Kind: "CronJob"}:    {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// NotReported marks referencing and referenced resources which are not reported as zombie. These are either
+// managed or skipped by the config (exclusions, namespaces, minimum age, ...), which can not be told apart.
+const NotReported = "not reported"
+
+// Reference is a reference from a resource to another resource in the same namespace.
+type Reference struct {
+	GroupKind schema.GroupKind
+	Namespace string
+	Name      string
+	// Ordinal references all resources named Name followed by a dash and an ordinal,
+	// like persistent volume claims created from StatefulSet volume claim templates.
+	Ordinal bool
+}
+
+// Matches returns true if the resource is the referenced one.
+func (r Reference) Matches(res unstructured.Unstructured) bool {
+	if res.GroupVersionKind().GroupKind() != r.GroupKind || res.GetNamespace() != r.Namespace {
+		return false
+	}
+
+	if !r.Ordinal {
+		return res.GetName() == r.Name
+	}
+
+	ordinal, ok := strings.CutPrefix(res.GetName(), r.Name+"-")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.Atoi(ordinal)
+	return err == nil
+}
+
+// References returns all references from a resource to other resources.
+func References(res unstructured.Unstructured) []Reference {
+	var refs []Reference
+	gk := res.GroupVersionKind().GroupKind()
+
+	if path, ok := podSpecPaths[gk]; ok {
+		refs = append(refs, podSpecReferences(res, path)...)
+	}
+
+	switch gk {
+	case schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}:
+		tls, _, _ := unstructured.NestedSlice(res.Object, "spec", "tls")
+		for _, entry := range tls {
+			refs = appendReference(refs, res, secretKind, asMap(entry), "secretName")
+		}
+	case serviceAccountKind:
+		for _, field := range []string{"secrets", "imagePullSecrets"} {
+			secrets, _, _ := unstructured.NestedSlice(res.Object, field)
+			for _, secret := range secrets {
+				refs = appendReference(refs, res, secretKind, asMap(secret), "name")
+			}
+		}
+	case schema.GroupKind{Group: "helm.toolkit.fluxcd.io", Kind: "HelmRelease"}:
+		refs = append(refs, valuesReferences(res, "spec", "valuesFrom")...)
+	case schema.GroupKind{Group: "kustomize.toolkit.fluxcd.io", Kind: "Kustomization"}:
+		refs = append(refs, valuesReferences(res, "spec", "postBuild", "substituteFrom")...)
+	case schema.GroupKind{Group: "fluxcd.controlplane.io", Kind: ResourceSetKind}:
+		// inputs selected by labels can not be resolved to a name
+		inputs, _, _ := unstructured.NestedSlice(res.Object, "spec", "inputsFrom")
+		for _, input := range inputs {
+			kind, _, _ := unstructured.NestedString(asMap(input), "kind")
+			if kind == inputProviderKind.Kind {
+				refs = appendReference(refs, res, inputProviderKind, asMap(input), "name")
+			}
+		}
+	}
+
+	if gk == (schema.GroupKind{Group: "apps", Kind: "StatefulSet"}) {
+		templates, _, _ := unstructured.NestedSlice(res.Object, "spec", "volumeClaimTemplates")
+		for _, template := range templates {
+			name, _, _ := unstructured.NestedString(asMap(template), "metadata", "name")
+			if name != "" {
+				refs = append(refs, Reference{
+					GroupKind: persistentVolumeClaimKind,
+					Namespace: res.GetNamespace(),
+					Name:      name + "-" + res.GetName(),
+					Ordinal:   true,
+				})
+			}
+		}
+	}
+
+	return refs
+}
+
+func appendReference(
+	refs []Reference,
+	res unstructured.Unstructured,
+	gk schema.GroupKind,
+	obj map[string]any,
+	field string,
+) []Reference {
+	name, _, _ := unstructured.NestedString(obj, field)
+	if name == "" {
+		return refs
+	}
+
+	return append(refs, Reference{GroupKind: gk, Namespace: res.GetNamespace(), Name: name})
+}
+
+// valuesReferences returns the references of flux value references (kind and name of a ConfigMap or Secret).
+func valuesReferences(res unstructured.Unstructured, path ...string) []Reference {
+	var refs []Reference
+	values, _, _ := unstructured.NestedSlice(res.Object, path...)
+	for _, value := range values {
+		kind, _, _ := unstructured.NestedString(asMap(value), "kind")
+		switch kind {
+		case configMapKind.Kind:
+			refs = appendReference(refs, res, configMapKind, asMap(value), "name")
+		case secretKind.Kind:
+			refs = appendReference(refs, res, secretKind, asMap(value), "name")
+		}
+	}
+
+	return refs
+}
+
+func podSpecReferences(res unstructured.Unstructured, path []string) []Reference {
+	raw, ok, _ := unstructured.NestedMap(res.Object, path...)
+	if !ok {
+		return nil
+	}
+
+	var spec corev1.PodSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &spec); err != nil {
+		return nil
+	}
+
+	var refs []Reference
+	add := func(gk schema.GroupKind, name string) {
+		if name == "" {
+			return
+		}
+
+		ref := Reference{GroupKind: gk, Namespace: res.GetNamespace(), Name: name}
+		for _, existing := range refs {
+			if existing == ref {
+				return
+			}
+		}
+
+		refs = append(refs, ref)
+	}
+
+	add(serviceAccountKind, spec.ServiceAccountName)
+	for _, secret := range spec.ImagePullSecrets {
+		add(secretKind, secret.Name)
+	}
+
+	for _, volume := range spec.Volumes {
+		switch {
+		case volume.ConfigMap != nil:
+			add(configMapKind, volume.ConfigMap.Name)
+		case volume.Secret != nil:
+			add(secretKind, volume.Secret.SecretName)
+		case volume.PersistentVolumeClaim != nil:
+			add(persistentVolumeClaimKind, volume.PersistentVolumeClaim.ClaimName)
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(configMapKind, source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					add(secretKind, source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add(configMapKind, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add(secretKind, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add(configMapKind, envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				add(secretKind, envFrom.SecretRef.Name)
+			}
+		}
+	}
+
+	return refs
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// ReferenceIndex resolves references between the resources of a cluster.
+type ReferenceIndex struct {
+	byNamespaceKind map[namespaceKind][]unstructured.Unstructured
+	referrers       map[namespaceKind][]referrer
+}
+
+type referrer struct {
+	ref Reference
+	res unstructured.Unstructured
+}
+
+type namespaceKind struct {
+	namespace string
+	kind      schema.GroupKind
+}
+
+// NewReferenceIndex builds a reference index from all resources listed on a cluster.
+func NewReferenceIndex(resources []unstructured.Unstructured) *ReferenceIndex {
+	index := &ReferenceIndex{
+		byNamespaceKind: make(map[namespaceKind][]unstructured.Unstructured),
+		referrers:       make(map[namespaceKind][]referrer),
+	}
+
+	for _, res := range resources {
+		key := namespaceKind{namespace: res.GetNamespace(), kind: res.GroupVersionKind().GroupKind()}
+		index.byNamespaceKind[key] = append(index.byNamespaceKind[key], res)
+
+		for _, ref := range References(res) {
+			key := namespaceKind{namespace: ref.Namespace, kind: ref.GroupKind}
+			index.referrers[key] = append(index.referrers[key], referrer{ref: ref, res: res})
+		}
+	}
+
+	return index
+}
+
+// ReferencedBy returns all resources referencing a resource.
+// Resources whose owner references the resource as well are omitted as their owner already represents them.
+func (i *ReferenceIndex) ReferencedBy(res unstructured.Unstructured) []unstructured.Unstructured {
+	var candidates []unstructured.Unstructured
+	seen := make(map[types.UID]struct{})
+	key := namespaceKind{namespace: res.GetNamespace(), kind: res.GroupVersionKind().GroupKind()}
+	for _, r := range i.referrers[key] {
+		// the same resource might have been listed in multiple api versions
+		if _, ok := seen[r.res.GetUID()]; ok || r.res.GetUID() == res.GetUID() || !r.ref.Matches(res) {
+			continue
+		}
+
+		seen[r.res.GetUID()] = struct{}{}
+		candidates = append(candidates, r.res)
+	}
+
+	var referencedBy []unstructured.Unstructured
+	for _, candidate := range candidates {
+		if owner := ControllerOf(candidate.GetOwnerReferences()); owner != nil {
+			if _, ok := seen[owner.UID]; ok {
+				continue
+			}
+		}
+
+		referencedBy = append(referencedBy, candidate)
+	}
+
+	return referencedBy
+}
+
+// AnnotateReferencedBy annotates each zombie with the resources referencing it.
+// Referencing resources which are not zombies themselves are marked as not reported.
+func (i *ReferenceIndex) AnnotateReferencedBy(zombies []unstructured.Unstructured) {
+	uids := make(map[types.UID]struct{}, len(zombies))
+	for _, zombie := range zombies {
+		uids[zombie.GetUID()] = struct{}{}
+	}
+
+	for j := range zombies {
+		var refs []string
+		for _, res := range i.ReferencedBy(zombies[j]) {
+			state := NotReported
+			if _, ok := uids[res.GetUID()]; ok {
+				state = "zombie"
+			}
+
+			refs = append(refs, fmt.Sprintf("%s [%s]", ObjectReference(res), state))
+		}
+
+		if len(refs) > 0 {
+			setAnnotation(&zombies[j], AnnotationReferencedBy, strings.Join(refs, ","))
+		}
+	}
+}
+
+// Dependents returns all resources referenced by a resource.
+func (i *ReferenceIndex) Dependents(res unstructured.Unstructured) []unstructured.Unstructured {
+	var dependents []unstructured.Unstructured
+	seen := make(map[types.UID]struct{})
+	for _, ref := range References(res) {
+		for _, candidate := range i.byNamespaceKind[namespaceKind{namespace: ref.Namespace, kind: ref.GroupKind}] {
+			// the same resource might have been listed in multiple api versions
+			if _, ok := seen[candidate.GetUID()]; ok || !ref.Matches(candidate) {
+				continue
+			}
+
+			seen[candidate.GetUID()] = struct{}{}
+			dependents = append(dependents, candidate)
+		}
+	}
+
+	return dependents
+}
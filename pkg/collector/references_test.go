@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newResource(apiVersion, kind, name, uid string) unstructured.Unstructured {
+	res := unstructured.Unstructured{Object: map[string]any{}}
+	res.SetAPIVersion(apiVersion)
+	res.SetKind(kind)
+	res.SetNamespace("test")
+	res.SetName(name)
+	res.SetUID(types.UID(uid))
+	return res
+}
+
+func TestReferences(t *testing.T) {
+	sts := newResource("apps/v1", "StatefulSet", "web", "1")
+	sts.Object["spec"] = map[string]any{
+		"template": map[string]any{
+			"spec": map[string]any{
+				"serviceAccountName": "web",
+				"volumes": []any{
+					map[string]any{"name": "config", "configMap": map[string]any{"name": "web-config"}},
+					map[string]any{"name": "tls", "secret": map[string]any{"secretName": "web-tls"}},
+				},
+				"containers": []any{
+					map[string]any{
+						"name": "web",
+						"envFrom": []any{
+							map[string]any{"secretRef": map[string]any{"name": "web-env"}},
+						},
+						"env": []any{
+							map[string]any{"name": "A", "valueFrom": map[string]any{
+								"configMapKeyRef": map[string]any{"name": "web-config", "key": "a"},
+							}},
+						},
+					},
+				},
+			},
+		},
+		"volumeClaimTemplates": []any{
+			map[string]any{"metadata": map[string]any{"name": "data"}},
+		},
+	}
+
+	refs := References(sts)
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.GroupKind.Kind+"/"+ref.Name)
+	}
+
+	assert.DeepEqual(t, []string{
+		"ServiceAccount/web",
+		"ConfigMap/web-config",
+		"Secret/web-tls",
+		"Secret/web-env",
+		"PersistentVolumeClaim/data-web",
+	}, names)
+
+	index := NewReferenceIndex([]unstructured.Unstructured{
+		sts,
+		newResource("v1", "ServiceAccount", "web", "2"),
+		newResource("v1", "ConfigMap", "web-config", "3"),
+		newResource("v1", "ConfigMap", "unrelated", "4"),
+		newResource("v1", "PersistentVolumeClaim", "data-web-0", "5"),
+		newResource("v1", "PersistentVolumeClaim", "data-web-3", "6"),
+		newResource("v1", "PersistentVolumeClaim", "data-web-config", "7"),
+	})
+
+	var dependents []string
+	for _, dependent := range index.Dependents(sts) {
+		dependents = append(dependents, ObjectReference(dependent))
+	}
+
+	assert.DeepEqual(t, []string{
+		"ServiceAccount/test/web",
+		"ConfigMap/test/web-config",
+		"PersistentVolumeClaim/test/data-web-0",
+		"PersistentVolumeClaim/test/data-web-3",
+	}, dependents)
+}
+
+func TestReferencedBy(t *testing.T) {
+	deployment := newResource("apps/v1", "Deployment", "web", "1")
+	deployment.Object["spec"] = map[string]any{
+		"template": map[string]any{
+			"spec": map[string]any{
+				"volumes": []any{
+					map[string]any{"name": "tls", "secret": map[string]any{"secretName": "web-tls"}},
+				},
+			},
+		},
+	}
+
+	replicaSet := newResource("apps/v1", "ReplicaSet", "web-1", "2")
+	replicaSet.Object["spec"] = deployment.Object["spec"]
+	replicaSet.SetOwnerReferences([]v1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "1"}})
+
+	ingress := newResource("networking.k8s.io/v1", "Ingress", "web", "3")
+	ingress.Object["spec"] = map[string]any{
+		"tls": []any{map[string]any{"secretName": "web-tls"}},
+	}
+
+	helmRelease := newResource("helm.toolkit.fluxcd.io/v2", "HelmRelease", "app", "4")
+	helmRelease.Object["spec"] = map[string]any{
+		"valuesFrom": []any{
+			map[string]any{"kind": "ConfigMap", "name": "app-values"},
+			map[string]any{"kind": "Secret", "name": "web-tls"},
+		},
+	}
+
+	kustomization := newResource("kustomize.toolkit.fluxcd.io/v1", "Kustomization", "apps", "5")
+	kustomization.Object["spec"] = map[string]any{
+		"postBuild": map[string]any{
+			"substituteFrom": []any{map[string]any{"kind": "ConfigMap", "name": "app-values"}},
+		},
+	}
+
+	resourceSet := newResource("fluxcd.controlplane.io/v1", "ResourceSet", "apps", "11")
+	resourceSet.Object["spec"] = map[string]any{
+		"inputsFrom": []any{
+			map[string]any{"kind": "ResourceSetInputProvider", "name": "tenants"},
+			map[string]any{"kind": "ResourceSetInputProvider", "selector": map[string]any{}},
+		},
+	}
+
+	serviceAccount := newResource("v1", "ServiceAccount", "web", "6")
+	serviceAccount.Object["imagePullSecrets"] = []any{map[string]any{"name": "registry"}}
+
+	secret := newResource("v1", "Secret", "web-tls", "7")
+	values := newResource("v1", "ConfigMap", "app-values", "8")
+	registry := newResource("v1", "Secret", "registry", "9")
+	unused := newResource("v1", "Secret", "unused", "10")
+	inputProvider := newResource("fluxcd.controlplane.io/v1", "ResourceSetInputProvider", "tenants", "12")
+
+	index := NewReferenceIndex([]unstructured.Unstructured{
+		deployment, replicaSet, ingress, helmRelease, kustomization, resourceSet, serviceAccount, secret, values, registry,
+		unused, inputProvider,
+	})
+
+	zombies := []unstructured.Unstructured{secret, values, registry, unused, ingress, inputProvider}
+	index.AnnotateReferencedBy(zombies)
+
+	assert.Equal(t,
+		"Deployment.apps/test/web [not reported],Ingress.networking.k8s.io/test/web [zombie],HelmRelease.helm.toolkit.fluxcd.io/test/app [not reported]",
+		secret.GetAnnotations()[AnnotationReferencedBy],
+	)
+	assert.Equal(t,
+		"HelmRelease.helm.toolkit.fluxcd.io/test/app [not reported],Kustomization.kustomize.toolkit.fluxcd.io/test/apps [not reported]",
+		values.GetAnnotations()[AnnotationReferencedBy],
+	)
+	assert.Equal(t, "ServiceAccount/test/web [not reported]", registry.GetAnnotations()[AnnotationReferencedBy])
+	assert.Equal(t, "", unused.GetAnnotations()[AnnotationReferencedBy])
+	assert.Equal(t,
+		"ResourceSet.fluxcd.controlplane.io/test/apps [not reported]",
+		inputProvider.GetAnnotations()[AnnotationReferencedBy],
+	)
+}
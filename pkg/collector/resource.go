@@ -3,15 +3,16 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"slices"
+	"time"
 
-	helmapi "github.com/fluxcd/helm-controller/api/v2"
-	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/cli-utils/pkg/object"
 
-	v1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
 )
 
 const (
@@ -19,6 +20,10 @@ const (
 	fluxHelmNamespaceLabel      = "helm.toolkit.fluxcd.io/namespace"
 	fluxKustomizeNameLabel      = "kustomize.toolkit.fluxcd.io/name"
 	fluxKustomizeNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	resourceSetNameLabel        = "resourceset.fluxcd.controlplane.io/name"
+	resourceSetNamespaceLabel   = "resourceset.fluxcd.controlplane.io/namespace"
+	fluxInstanceNameLabel       = "fluxcd.controlplane.io/name"
+	fluxInstanceNamespaceLabel  = "fluxcd.controlplane.io/namespace"
 )
 
 // FilterFunc is a function that filters resources.
@@ -91,9 +96,14 @@ func IgnoreServiceAccountSecret() FilterFunc {
 }
 
 // IgnoreHelmSecret returns a FilterFunc which filters secrets owned by helm.
+// Storage secrets reporting a helm release (see ReportHelmReleases) are kept.
 func IgnoreHelmSecret() FilterFunc {
 	return func(res unstructured.Unstructured, _ klog.Logger) bool {
 		if res.GetKind() == "Secret" && res.GetAPIVersion() == "v1" {
+			if _, ok := res.GetAnnotations()[AnnotationHelmRelease]; ok {
+				return false
+			}
+
 			if v, ok := res.GetLabels()["owner"]; ok && v == "helm" {
 				return true
 			}
@@ -103,13 +113,32 @@ func IgnoreHelmSecret() FilterFunc {
 	}
 }
 
+// IgnoreYoungerThan returns a FilterFunc which filters resources created less than minAge ago.
+// Such resources are likely still in the middle of being reconciled.
+func IgnoreYoungerThan(minAge time.Duration) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		created := res.GetCreationTimestamp()
+		if minAge <= 0 || created.IsZero() {
+			return false
+		}
+
+		if time.Since(created.Time) < minAge {
+			logger.V(1).
+				Info("ignore resource younger than min age", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "minAge", minAge)
+			return true
+		}
+
+		return false
+	}
+}
+
 // IgnoreIfHelmReleaseFound returns a FilterFunc which filters resources part of an helm release.
-func IgnoreIfHelmReleaseFound(helmReleases []helmapi.HelmRelease) FilterFunc {
+func IgnoreIfHelmReleaseFound(index *FluxIndex) FilterFunc {
 	return func(res unstructured.Unstructured, logger klog.Logger) bool {
 		labels := res.GetLabels()
 		if helmName, ok := labels[fluxHelmNameLabel]; ok {
 			if helmNamespace, ok := labels[fluxHelmNamespaceLabel]; ok {
-				if hasResource(helmReleases, helmName, helmNamespace) {
+				if index.HasHelmRelease(helmName, helmNamespace) {
 					return true
 				}
 
@@ -123,7 +152,7 @@ func IgnoreIfHelmReleaseFound(helmReleases []helmapi.HelmRelease) FilterFunc {
 }
 
 // IgnoreIfKustomizationFound returns a FilterFunc which filters resources part of a flux kustomization.
-func IgnoreIfKustomizationFound(kustomizations []ksapi.Kustomization) FilterFunc {
+func IgnoreIfKustomizationFound(index *FluxIndex) FilterFunc {
 	return func(res unstructured.Unstructured, logger klog.Logger) bool {
 		labels := res.GetLabels()
 		ksName, okKsName := labels[fluxKustomizeNameLabel]
@@ -132,7 +161,7 @@ func IgnoreIfKustomizationFound(kustomizations []ksapi.Kustomization) FilterFunc
 			return false
 		}
 
-		if ks := findKustomization(kustomizations, ksName, ksNamespace); ks != nil {
+		if index.HasKustomization(ksName, ksNamespace) {
 			obj := object.ObjMetadata{
 				Namespace: res.GetNamespace(),
 				Name:      res.GetName(),
@@ -143,12 +172,8 @@ func IgnoreIfKustomizationFound(kustomizations []ksapi.Kustomization) FilterFunc
 			logger.V(1).
 				Info("lookup kustomization inventory", "kustomizationName", ksName, "kustomizationNamespace", ksNamespace, "resourceId", id)
 
-			if ks.Status.Inventory != nil {
-				for _, entry := range ks.Status.Inventory.Entries {
-					if entry.ID == id {
-						return true
-					}
-				}
+			if index.InventoryContains(ksName, ksNamespace, id) {
+				return true
 			}
 
 			logger.V(1).
@@ -161,127 +186,132 @@ func IgnoreIfKustomizationFound(kustomizations []ksapi.Kustomization) FilterFunc
 	}
 }
 
-// IgnoreRuleExclusions returns a FilterFunc which excludes resources part of configuration exclusions.
-func IgnoreRuleExclusions(cluster string, exclusions []v1.ExcludeResources) FilterFunc {
-	return func(res unstructured.Unstructured, _ klog.Logger) bool {
-		for _, exclusion := range exclusions {
-			if !matchesCluster(cluster, exclusion.Cluster) {
-				continue
-			}
+// IgnoreIfResourceSetFound returns a FilterFunc which filters resources part of a flux operator ResourceSet.
+func IgnoreIfResourceSetFound(index *FluxIndex) FilterFunc {
+	return ignoreIfInventoryFound(index, ResourceSetKind, resourceSetNameLabel, resourceSetNamespaceLabel)
+}
 
-			if !resourceMatchesGetAPIVersionAndKind(res, exclusion.APIVersion, exclusion.Kind) {
-				continue
-			}
+// IgnoreIfFluxInstanceFound returns a FilterFunc which filters resources part of a flux operator FluxInstance.
+func IgnoreIfFluxInstanceFound(index *FluxIndex) FilterFunc {
+	return ignoreIfInventoryFound(index, FluxInstanceKind, fluxInstanceNameLabel, fluxInstanceNamespaceLabel)
+}
 
-			if !resourceMatchesNamespace(res, exclusion.Namespace) {
-				continue
-			}
+// ignoreIfInventoryFound filters resources labeled with a flux operator object which holds them in its inventory.
+func ignoreIfInventoryFound(index *FluxIndex, kind, nameLabel, namespaceLabel string) FilterFunc {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		labels := res.GetLabels()
+		name, okName := labels[nameLabel]
+		namespace, okNamespace := labels[namespaceLabel]
+		if !okName || !okNamespace {
+			return false
+		}
 
-			if !resourceMatchesMetadata(res.GetAnnotations(), exclusion.Annotations) {
-				continue
-			}
+		if !index.HasInventoryOwner(kind, name, namespace) {
+			logger.V(1).
+				Info("inventory owner not found from resource", "resource", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "ownerKind", kind, "ownerName", name, "ownerNamespace", namespace)
+			return false
+		}
 
-			if !resourceMatchesMetadata(res.GetLabels(), exclusion.Labels) {
-				continue
-			}
+		id := object.ObjMetadata{
+			Namespace: res.GetNamespace(),
+			Name:      res.GetName(),
+			GroupKind: res.GroupVersionKind().GroupKind(),
+		}.String()
 
-			if resourceMatchesName(res, exclusion.Name) {
-				return true
-			}
-		}
-		return false
-	}
-}
+		logger.V(1).
+			Info("lookup inventory", "ownerKind", kind, "ownerName", name, "ownerNamespace", namespace, "resourceId", id)
 
-func matchesCluster(cluster, clusterExclude string) bool {
-	if clusterExclude != "" {
-		match, err := regexp.MatchString(`^`+clusterExclude+`$`, cluster)
-		if err != nil {
-			klog.Error(err)
+		if index.OwnerInventoryContains(kind, name, namespace, id) {
+			return true
 		}
 
-		return match
+		logger.V(1).
+			Info("resource is not part of the inventory", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "ownerKind", kind, "ownerName", name, "ownerNamespace", namespace)
+		return false
 	}
-
-	return true
 }
 
-func resourceMatchesGetAPIVersionAndKind(res unstructured.Unstructured, apiVersion, kind string) bool {
-	// match all api versions
-	resVer := res.GetAPIVersion()
-	if apiVersion != "" && resVer != apiVersion {
-		return false
+// IgnoreRuleExclusions returns a FilterFunc which excludes resources part of configuration exclusions.
+// It fails if any pattern or expression of the exclusions applying to the cluster does not compile.
+func IgnoreRuleExclusions(cluster string, exclusions []v2.ResourceRule) (FilterFunc, error) {
+	rules, err := compileRules(cluster, exclusions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclusion: %w", err)
 	}
 
-	if kind != "" && res.GetKind() != kind {
-		return false
-	}
+	return func(res unstructured.Unstructured, _ klog.Logger) bool {
+		for range rules.matching(res) {
+			return true
+		}
 
-	return true
+		return false
+	}, nil
 }
 
-func resourceMatchesNamespace(res unstructured.Unstructured, namespace string) bool {
-	if namespace != "" {
-		match, err := regexp.MatchString(`^`+namespace+`$`, res.GetNamespace())
-		if err != nil {
-			klog.Error(err)
-		}
-		if !match {
-			return false
-		}
+// IgnoreIfNotIncluded returns a FilterFunc which excludes resources not part of configuration inclusions.
+// All resources are included if there are no inclusions. The severity of the first matching inclusion is assigned.
+// It fails if any pattern or expression of the inclusions applying to the cluster does not compile.
+func IgnoreIfNotIncluded(cluster string, inclusions []v2.ResourceRule) (FilterFunc, error) {
+	rules, err := compileRules(cluster, inclusions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inclusion: %w", err)
 	}
 
-	return true
-}
-
-func resourceMatchesMetadata(resMetadata, metadata map[string]string) bool {
-	for key, val := range metadata {
-		v, ok := resMetadata[key]
-		if !ok {
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		if len(inclusions) == 0 {
 			return false
 		}
 
-		match, err := regexp.MatchString(`^`+val+`$`, v)
-		if err != nil {
-			klog.Error(err)
-		}
-		if !match {
+		for inclusion := range rules.matching(res) {
+			if inclusion.rule.Severity != "" {
+				setAnnotation(&res, AnnotationSeverity, string(inclusion.rule.Severity))
+			}
+
 			return false
 		}
-	}
 
-	return true
+		logger.V(1).
+			Info("ignore resource not matching any inclusion", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion())
+		return true
+	}, nil
 }
 
-func resourceMatchesName(res unstructured.Unstructured, name string) bool {
-	if name != "" {
-		match, err := regexp.MatchString(`^`+name+`$`, res.GetName())
-		if err != nil {
-			klog.Error(err)
-		}
+// IgnoreNamespaces returns a FilterFunc which excludes namespaced resources whose namespace does not match
+// any of the included namespaces (if there are any) or matches any of the excluded ones.
+// Cluster scoped resources are not affected. It fails if any of the namespace patterns does not compile.
+func IgnoreNamespaces(include, exclude []string) (FilterFunc, error) {
+	includePatterns, err := v2.CompileNamespacePatterns(include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid included namespace: %w", err)
+	}
 
-		return match
+	excludePatterns, err := v2.CompileNamespacePatterns(exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid excluded namespace: %w", err)
 	}
 
-	return true
-}
+	return func(res unstructured.Unstructured, logger klog.Logger) bool {
+		namespace := res.GetNamespace()
+		if namespace == "" {
+			return false
+		}
 
-func hasResource(pool []helmapi.HelmRelease, name, namespace string) bool {
-	for _, res := range pool {
-		if res.GetName() == name && res.GetNamespace() == namespace {
-			return true
+		matchesNamespace := func(re *regexp.Regexp) bool {
+			return re.MatchString(namespace)
 		}
-	}
 
-	return false
-}
+		if len(includePatterns) > 0 && !slices.ContainsFunc(includePatterns, matchesNamespace) {
+			logger.V(1).
+				Info("ignore resource in namespace which is not included", "name", res.GetName(), "namespace", namespace, "apiVersion", res.GetAPIVersion())
+			return true
+		}
 
-func findKustomization(pool []ksapi.Kustomization, name, namespace string) *ksapi.Kustomization {
-	for _, res := range pool {
-		if res.GetName() == name && res.GetNamespace() == namespace {
-			return &res
+		if slices.ContainsFunc(excludePatterns, matchesNamespace) {
+			logger.V(1).
+				Info("ignore resource in excluded namespace", "name", res.GetName(), "namespace", namespace, "apiVersion", res.GetAPIVersion())
+			return true
 		}
-	}
 
-	return nil
+		return false
+	}, nil
 }
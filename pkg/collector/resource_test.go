@@ -2,6 +2,7 @@ package collector
 
 import (
 	"testing"
+	"time"
 
 	helmapi "github.com/fluxcd/helm-controller/api/v2"
 	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
@@ -12,7 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 
-	gitopszombiesv1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
 )
 
 type NullLogger struct{}
@@ -23,9 +24,32 @@ func (l NullLogger) Debugf(_ string, _ ...any) {
 func (l NullLogger) Failuref(_ string, _ ...any) {
 }
 
+func mustFilter(t *testing.T, filter FilterFunc, err error) FilterFunc {
+	t.Helper()
+	require.NoError(t, err)
+	return filter
+}
+
+func newInventoryOwner(kind, name, namespace string, ids ...string) unstructured.Unstructured {
+	entries := make([]any, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, map[string]any{"id": id, "v": "v1"})
+	}
+
+	owner := unstructured.Unstructured{Object: map[string]any{
+		"status": map[string]any{"inventory": map[string]any{"entries": entries}},
+	}}
+	owner.SetAPIVersion("fluxcd.controlplane.io/v1")
+	owner.SetKind(kind)
+	owner.SetName(name)
+	owner.SetNamespace(namespace)
+
+	return owner
+}
+
 type test struct {
 	name         string
-	filters      func() []FilterFunc
+	filters      func(t *testing.T) []FilterFunc
 	list         func() *unstructured.UnstructuredList
 	expectedPass int
 }
@@ -71,7 +95,7 @@ func TestDiscovery(t *testing.T) {
 	tests := []test{
 		{
 			name: "A resource which has owner references is skipped",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				return []FilterFunc{IgnoreOwnedResource()}
 			},
 			list: func() *unstructured.UnstructuredList {
@@ -94,7 +118,7 @@ func TestDiscovery(t *testing.T) {
 		},
 		{
 			name: "A secret which belongs to a service account is ignored",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				return []FilterFunc{IgnoreServiceAccountSecret()}
 			},
 			list: func() *unstructured.UnstructuredList {
@@ -119,7 +143,7 @@ func TestDiscovery(t *testing.T) {
 		},
 		{
 			name: "A secret which is labeled as a helm owner is ignored",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				return []FilterFunc{IgnoreHelmSecret()}
 			},
 			list: func() *unstructured.UnstructuredList {
@@ -142,9 +166,112 @@ func TestDiscovery(t *testing.T) {
 			},
 			expectedPass: 1,
 		},
+		{
+			name: "A resource younger than the min age is ignored",
+			filters: func(t *testing.T) []FilterFunc {
+				return []FilterFunc{IgnoreYoungerThan(24 * time.Hour)}
+			},
+			list: func() *unstructured.UnstructuredList {
+				list := &unstructured.UnstructuredList{}
+				expected := unstructured.Unstructured{}
+				expected.SetName("old-resource")
+				expected.SetCreationTimestamp(v1.NewTime(time.Now().Add(-48 * time.Hour)))
+
+				alsoExpected := unstructured.Unstructured{}
+				alsoExpected.SetName("resource-without-timestamp")
+
+				notExpected := unstructured.Unstructured{}
+				notExpected.SetName("fresh-resource")
+				notExpected.SetCreationTimestamp(v1.NewTime(time.Now().Add(-time.Hour)))
+
+				list.Items = append(list.Items, expected, alsoExpected, notExpected)
+				return list
+			},
+			expectedPass: 2,
+		},
+		{
+			name: "A resource server-side applied by a flux controller is ignored",
+			filters: func(t *testing.T) []FilterFunc {
+				return []FilterFunc{IgnoreIfAppliedByFlux()}
+			},
+			list: func() *unstructured.UnstructuredList {
+				list := &unstructured.UnstructuredList{}
+				expected := unstructured.Unstructured{}
+				expected.SetName("resource")
+				expected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "kubectl-client-side-apply", Operation: v1.ManagedFieldsOperationUpdate},
+				})
+
+				alsoExpected := unstructured.Unstructured{}
+				alsoExpected.SetName("resource-updated-by-flux")
+				alsoExpected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "kustomize-controller", Operation: v1.ManagedFieldsOperationUpdate},
+				})
+
+				notExpected := unstructured.Unstructured{}
+				notExpected.SetName("resource-applied-by-flux")
+				notExpected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "kustomize-controller", Operation: v1.ManagedFieldsOperationApply},
+				})
+
+				list.Items = append(list.Items, expected, alsoExpected, notExpected)
+				return list
+			},
+			expectedPass: 2,
+		},
+		{
+			name: "A managed resource which was modified manually is reported",
+			filters: func(t *testing.T) []FilterFunc {
+				helmReleases := []helmapi.HelmRelease{}
+				hr := helmapi.HelmRelease{}
+				hr.SetName("release")
+				hr.SetNamespace("test")
+
+				helmReleases = append(helmReleases, hr)
+
+				return []FilterFunc{
+					ReportManuallyModified([]string{"my-operator"}, IgnoreIfHelmReleaseFound(NewFluxIndex(helmReleases, nil))),
+				}
+			},
+			list: func() *unstructured.UnstructuredList {
+				labels := map[string]string{
+					fluxHelmNameLabel:      "release",
+					fluxHelmNamespaceLabel: "test",
+				}
+
+				list := &unstructured.UnstructuredList{}
+				expected := unstructured.Unstructured{}
+				expected.SetName("edited")
+				expected.SetLabels(labels)
+				expected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "helm-controller", Operation: v1.ManagedFieldsOperationUpdate},
+					{Manager: "kubectl-edit", Operation: v1.ManagedFieldsOperationUpdate},
+				})
+
+				alsoExpected := unstructured.Unstructured{}
+				alsoExpected.SetName("unknown-manager")
+				alsoExpected.SetLabels(labels)
+				alsoExpected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "some-script", Operation: v1.ManagedFieldsOperationUpdate},
+				})
+
+				notExpected := unstructured.Unstructured{}
+				notExpected.SetName("trusted-manager")
+				notExpected.SetLabels(labels)
+				notExpected.SetManagedFields([]v1.ManagedFieldsEntry{
+					{Manager: "helm-controller", Operation: v1.ManagedFieldsOperationUpdate},
+					{Manager: "my-operator", Operation: v1.ManagedFieldsOperationUpdate},
+					{Manager: "kubectl-patch", Operation: v1.ManagedFieldsOperationUpdate, Subresource: "status"},
+				})
+
+				list.Items = append(list.Items, expected, alsoExpected, notExpected)
+				return list
+			},
+			expectedPass: 2,
+		},
 		{
 			name: "A resource which is part of a helmrelease is ignored",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				helmReleases := []helmapi.HelmRelease{}
 				hr := helmapi.HelmRelease{}
 				hr.SetName("release")
@@ -152,7 +279,7 @@ func TestDiscovery(t *testing.T) {
 
 				helmReleases = append(helmReleases, hr)
 
-				return []FilterFunc{IgnoreIfHelmReleaseFound(helmReleases)}
+				return []FilterFunc{IgnoreIfHelmReleaseFound(NewFluxIndex(helmReleases, nil))}
 			},
 			list: func() *unstructured.UnstructuredList {
 				list := &unstructured.UnstructuredList{}
@@ -180,7 +307,7 @@ func TestDiscovery(t *testing.T) {
 		},
 		{
 			name: "A resource which is part of a kustomization but without a matching inventory entry is not ignored",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				kustomizations := &ksapi.KustomizationList{}
 				ks := ksapi.Kustomization{}
 				ks.SetName("release")
@@ -188,7 +315,7 @@ func TestDiscovery(t *testing.T) {
 
 				kustomizations.Items = append(kustomizations.Items, ks)
 
-				return []FilterFunc{IgnoreIfKustomizationFound(kustomizations.Items)}
+				return []FilterFunc{IgnoreIfKustomizationFound(NewFluxIndex(nil, kustomizations.Items))}
 			},
 			list: func() *unstructured.UnstructuredList {
 				list := &unstructured.UnstructuredList{}
@@ -209,7 +336,7 @@ func TestDiscovery(t *testing.T) {
 		},
 		{
 			name: "A resource which is part of a kustomization and has a valid matching inventory entry is ignored",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				kustomizations := &ksapi.KustomizationList{}
 				ks := ksapi.Kustomization{}
 				ks.SetName("release")
@@ -224,7 +351,7 @@ func TestDiscovery(t *testing.T) {
 
 				kustomizations.Items = append(kustomizations.Items, ks)
 
-				return []FilterFunc{IgnoreIfKustomizationFound(kustomizations.Items)}
+				return []FilterFunc{IgnoreIfKustomizationFound(NewFluxIndex(nil, kustomizations.Items))}
 			},
 			list: func() *unstructured.UnstructuredList {
 				list := &unstructured.UnstructuredList{}
@@ -258,7 +385,7 @@ func TestDiscovery(t *testing.T) {
 		},
 		{
 			name: "A resource which is part of a kustomization but the kustomization was not found",
-			filters: func() []FilterFunc {
+			filters: func(t *testing.T) []FilterFunc {
 				kustomizations := &ksapi.KustomizationList{}
 				ks := ksapi.Kustomization{}
 				ks.SetName("release")
@@ -273,7 +400,7 @@ func TestDiscovery(t *testing.T) {
 
 				kustomizations.Items = append(kustomizations.Items, ks)
 
-				return []FilterFunc{IgnoreIfKustomizationFound(kustomizations.Items)}
+				return []FilterFunc{IgnoreIfKustomizationFound(NewFluxIndex(nil, kustomizations.Items))}
 			},
 			list: func() *unstructured.UnstructuredList {
 				list := &unstructured.UnstructuredList{}
@@ -302,166 +429,337 @@ func TestDiscovery(t *testing.T) {
 			},
 			expectedPass: 1,
 		},
+		{
+			name: "A resource which is part of a resourceset and has a valid matching inventory entry is ignored",
+			filters: func(t *testing.T) []FilterFunc {
+				index := NewFluxIndex(nil, nil)
+				index.AddInventoryOwners([]unstructured.Unstructured{
+					newInventoryOwner(ResourceSetKind, "apps", "test", "test_cluster-role__test_rbac.authorization.k8s.io_ClusterRole"),
+				})
+
+				return []FilterFunc{IgnoreIfResourceSetFound(index)}
+			},
+			list: func() *unstructured.UnstructuredList {
+				list := &unstructured.UnstructuredList{}
+				expected := unstructured.Unstructured{}
+				expected.SetName("resource")
+
+				alsoExpected := unstructured.Unstructured{}
+				alsoExpected.SetName("service-account-secret")
+				alsoExpected.SetLabels(map[string]string{
+					resourceSetNameLabel:      "apps",
+					resourceSetNamespaceLabel: "test",
+				})
+
+				notExpected := unstructured.Unstructured{}
+				notExpected.SetGroupVersionKind(schema.GroupVersionKind{
+					Group:   "rbac.authorization.k8s.io",
+					Version: "v1",
+					Kind:    "ClusterRole",
+				})
+				notExpected.SetNamespace("test")
+				notExpected.SetName("cluster-role:test")
+				notExpected.SetLabels(map[string]string{
+					resourceSetNameLabel:      "apps",
+					resourceSetNamespaceLabel: "test",
+				})
+
+				list.Items = append(list.Items, expected, alsoExpected, notExpected)
+				return list
+			},
+			expectedPass: 2,
+		},
+		{
+			name: "A resource which is part of a resourceset but the resourceset was not found",
+			filters: func(t *testing.T) []FilterFunc {
+				index := NewFluxIndex(nil, nil)
+				index.AddInventoryOwners([]unstructured.Unstructured{
+					newInventoryOwner(FluxInstanceKind, "apps", "test", "test_service-account-secret__Secret"),
+				})
+
+				return []FilterFunc{IgnoreIfResourceSetFound(index)}
+			},
+			list: func() *unstructured.UnstructuredList {
+				list := &unstructured.UnstructuredList{}
+				expected := unstructured.Unstructured{}
+				expected.SetGroupVersionKind(schema.GroupVersionKind{
+					Group:   "",
+					Version: "v1",
+					Kind:    "Secret",
+				})
+				expected.SetNamespace("test")
+				expected.SetName("service-account-secret")
+				expected.SetLabels(map[string]string{
+					resourceSetNameLabel:      "apps",
+					resourceSetNamespaceLabel: "test",
+				})
+
+				list.Items = append(list.Items, expected)
+				return list
+			},
+			expectedPass: 1,
+		},
+		{
+			name: "A resource which is part of a fluxinstance and has a valid matching inventory entry is ignored",
+			filters: func(t *testing.T) []FilterFunc {
+				index := NewFluxIndex(nil, nil)
+				index.AddInventoryOwners([]unstructured.Unstructured{
+					newInventoryOwner(FluxInstanceKind, "flux", "flux-system", "flux-system_source-controller_apps_Deployment"),
+				})
+
+				return []FilterFunc{IgnoreIfFluxInstanceFound(index)}
+			},
+			list: func() *unstructured.UnstructuredList {
+				list := &unstructured.UnstructuredList{}
+				notExpected := unstructured.Unstructured{}
+				notExpected.SetGroupVersionKind(schema.GroupVersionKind{
+					Group:   "apps",
+					Version: "v1",
+					Kind:    "Deployment",
+				})
+				notExpected.SetNamespace("flux-system")
+				notExpected.SetName("source-controller")
+				notExpected.SetLabels(map[string]string{
+					fluxInstanceNameLabel:      "flux",
+					fluxInstanceNamespaceLabel: "flux-system",
+				})
+
+				expected := notExpected.DeepCopy()
+				expected.SetName("image-reflector-controller")
+
+				list.Items = append(list.Items, notExpected, *expected)
+				return list
+			},
+			expectedPass: 1,
+		},
 		{
 			name: "Resources excluded from conf: match all",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 0,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by cluster",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Cluster: "test",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 0,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by cluster (regexp)",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Cluster: "t.*",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 0,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by apiVersion",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						TypeMeta: v1.TypeMeta{APIVersion: "velero.io/v1"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 1,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by apiVersion and kind",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						TypeMeta: v1.TypeMeta{APIVersion: "velero.io/v1", Kind: "Backup"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by namespace",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Namespace: "velero",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 1,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by namespace (regexp)",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Namespace: "v.*",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 0,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by annotation",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Annotations: map[string]string{"test-annotation": "velero-capi-backup-1"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by annotation (regexp)",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Annotations: map[string]string{"test-annotation": "v.*"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by label",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Labels: map[string]string{"test-label": "velero-capi-backup-2"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by label (regexp)",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Labels: map[string]string{"test-label": "v.*"},
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by name",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Name: "velero-capi-backup-1",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 2,
 		},
 		{
 			name: "Resources excluded from conf: match restricted by name (regexp)",
-			filters: func() []FilterFunc {
-				return []FilterFunc{IgnoreRuleExclusions("test", []gitopszombiesv1.ExcludeResources{
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreRuleExclusions("test", []v2.ResourceRule{
 					{
 						Name: "velero-capi-backup-(1|2)",
 					},
-				})}
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
 			},
 			list:         getExclusionListResourceSet,
 			expectedPass: 1,
 		},
+		{
+			name: "Resources included from conf: match restricted by kind",
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreIfNotIncluded("test", []v2.ResourceRule{
+					{
+						TypeMeta: v1.TypeMeta{
+							Kind: "Backuped",
+						},
+					},
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
+			},
+			list:         getExclusionListResourceSet,
+			expectedPass: 1,
+		},
+		{
+			name: "Resources included from conf: match restricted by cluster",
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreIfNotIncluded("test", []v2.ResourceRule{
+					{
+						Cluster: "prod",
+					},
+				})
+				return []FilterFunc{mustFilter(t, filter, err)}
+			},
+			list:         getExclusionListResourceSet,
+			expectedPass: 0,
+		},
+		{
+			name: "Resources in included namespaces (regexp)",
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreNamespaces([]string{"velero[0-9]"}, nil)
+				return []FilterFunc{mustFilter(t, filter, err)}
+			},
+			list:         getExclusionListResourceSet,
+			expectedPass: 1,
+		},
+		{
+			name: "Resources in excluded namespaces",
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreNamespaces([]string{"velero.*"}, []string{"velero2"})
+				return []FilterFunc{mustFilter(t, filter, err)}
+			},
+			list:         getExclusionListResourceSet,
+			expectedPass: 2,
+		},
+		{
+			name: "Resources in excluded namespaces (glob)",
+			filters: func(t *testing.T) []FilterFunc {
+				filter, err := IgnoreNamespaces([]string{"velero*"}, []string{"*2"})
+				return []FilterFunc{mustFilter(t, filter, err)}
+			},
+			list:         getExclusionListResourceSet,
+			expectedPass: 2,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			ch := make(chan unstructured.Unstructured, test.expectedPass+1)
-			discovery := NewDiscovery(klog.NewKlogr(), test.filters()...)
+			discovery := NewDiscovery(klog.NewKlogr(), test.filters(t)...)
 			err := discovery.Discover(t.Context(), test.list(), ch)
 			require.NoError(t, err)
 			assert.Equal(t, test.expectedPass, len(ch))
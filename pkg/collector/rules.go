@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"sync/atomic"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+// pattern is a precompiled regular expression matching whole values, an empty pattern matches everything.
+type pattern struct {
+	re *regexp.Regexp
+}
+
+func compilePattern(expr string) (pattern, error) {
+	if expr == "" {
+		return pattern{}, nil
+	}
+
+	re, err := regexp.Compile(`^` + expr + `$`)
+	if err != nil {
+		return pattern{}, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+
+	return pattern{re: re}, nil
+}
+
+func (p pattern) MatchString(s string) bool {
+	return p.re == nil || p.re.MatchString(s)
+}
+
+// compiledRule is a resource rule with all its patterns and its expression compiled.
+type compiledRule struct {
+	rule        v2.ResourceRule
+	namespace   pattern
+	name        pattern
+	annotations map[string]pattern
+	labels      map[string]pattern
+	expression  cel.Program
+	// evalFailed is set once an evaluation of the expression failed, only the first failure is logged as error
+	evalFailed *atomic.Bool
+}
+
+// ruleKey indexes rules by apiVersion and kind, empty values are wildcards.
+type ruleKey struct {
+	apiVersion string
+	kind       string
+}
+
+// ruleSet holds the compiled rules applying to a cluster indexed by their apiVersion and kind.
+// Only the rules which may apply to the apiVersion and kind of a resource are evaluated.
+type ruleSet struct {
+	rules []compiledRule
+	index map[ruleKey][]int
+}
+
+// compileRules compiles the rules applying to the cluster. Rules restricted to other clusters are dropped.
+func compileRules(cluster string, rules []v2.ResourceRule) (*ruleSet, error) {
+	set := &ruleSet{index: make(map[ruleKey][]int)}
+	for i, rule := range rules {
+		clusterPattern, err := compilePattern(rule.Cluster)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: cluster: %w", i, err)
+		}
+
+		if !clusterPattern.MatchString(cluster) {
+			continue
+		}
+
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		key := ruleKey{apiVersion: rule.APIVersion, kind: rule.Kind}
+		set.index[key] = append(set.index[key], len(set.rules))
+		set.rules = append(set.rules, compiled)
+	}
+
+	return set, nil
+}
+
+func compileRule(rule v2.ResourceRule) (compiledRule, error) {
+	var err error
+	compiled := compiledRule{rule: rule}
+
+	if compiled.namespace, err = compilePattern(rule.Namespace); err != nil {
+		return compiled, fmt.Errorf("namespace: %w", err)
+	}
+
+	if compiled.name, err = compilePattern(rule.Name); err != nil {
+		return compiled, fmt.Errorf("name: %w", err)
+	}
+
+	if compiled.annotations, err = compileMetadataPatterns(rule.Annotations); err != nil {
+		return compiled, fmt.Errorf("annotations: %w", err)
+	}
+
+	if compiled.labels, err = compileMetadataPatterns(rule.Labels); err != nil {
+		return compiled, fmt.Errorf("labels: %w", err)
+	}
+
+	if rule.Expression != "" {
+		if compiled.expression, err = CompileExpression(rule.Expression); err != nil {
+			return compiled, fmt.Errorf("expression: %w", err)
+		}
+
+		compiled.evalFailed = &atomic.Bool{}
+	}
+
+	return compiled, nil
+}
+
+func compileMetadataPatterns(metadata map[string]string) (map[string]pattern, error) {
+	patterns := make(map[string]pattern, len(metadata))
+	for key, expr := range metadata {
+		p, err := compilePattern(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		patterns[key] = p
+	}
+
+	return patterns, nil
+}
+
+// matching iterates over the rules matching the resource in their configured order.
+func (s *ruleSet) matching(res unstructured.Unstructured) iter.Seq[*compiledRule] {
+	return func(yield func(*compiledRule) bool) {
+		apiVersion, kind := res.GetAPIVersion(), res.GetKind()
+
+		var candidates []int
+		for _, key := range []ruleKey{
+			{apiVersion: apiVersion, kind: kind},
+			{apiVersion: apiVersion},
+			{kind: kind},
+			{},
+		} {
+			candidates = append(candidates, s.index[key]...)
+		}
+
+		// a resource with an empty apiVersion or kind looks up the same key multiple times
+		slices.Sort(candidates)
+		candidates = slices.Compact(candidates)
+
+		for _, i := range candidates {
+			if s.rules[i].matches(res) && !yield(&s.rules[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (r *compiledRule) matches(res unstructured.Unstructured) bool {
+	if !r.namespace.MatchString(res.GetNamespace()) {
+		return false
+	}
+
+	if !resourceMatchesMetadata(res.GetAnnotations(), r.annotations) {
+		return false
+	}
+
+	if !resourceMatchesMetadata(res.GetLabels(), r.labels) {
+		return false
+	}
+
+	if !r.name.MatchString(res.GetName()) {
+		return false
+	}
+
+	if r.expression == nil {
+		return true
+	}
+
+	match, err := evalExpression(r.expression, res)
+	switch {
+	case errors.Is(err, errExpressionCostLimit):
+		klog.Errorf("rule expression %q rejected for %s %s/%s: %s", r.rule.Expression, res.GetKind(), res.GetNamespace(), res.GetName(), err)
+	case err != nil && !r.evalFailed.Swap(true):
+		klog.Errorf("failed to evaluate rule expression %q for %s %s/%s, further failures are logged at verbosity 1: %s", r.rule.Expression, res.GetKind(), res.GetNamespace(), res.GetName(), err)
+	case err != nil:
+		klog.V(1).
+			Info("failed to evaluate rule expression", "name", res.GetName(), "namespace", res.GetNamespace(), "apiVersion", res.GetAPIVersion(), "expression", r.rule.Expression, "error", err)
+	}
+
+	return match
+}
+
+func resourceMatchesMetadata(resMetadata map[string]string, metadata map[string]pattern) bool {
+	for key, p := range metadata {
+		v, ok := resMetadata[key]
+		if !ok || !p.MatchString(v) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestCompileRulesFailsOnInvalidPattern(t *testing.T) {
+	_, err := IgnoreRuleExclusions("test", []v2.ResourceRule{{Name: "web-("}})
+	require.Error(t, err)
+
+	_, err = IgnoreIfNotIncluded("test", []v2.ResourceRule{{Labels: map[string]string{"app": "["}}})
+	require.Error(t, err)
+
+	_, err = IgnoreNamespaces(nil, []string{"kube-("})
+	require.Error(t, err)
+
+	// rules restricted to other clusters are not compiled
+	_, err = IgnoreRuleExclusions("test", []v2.ResourceRule{{Cluster: "other", Name: "web-("}})
+	require.NoError(t, err)
+}
+
+func TestRuleSetMatching(t *testing.T) {
+	rule := func(apiVersion, kind, name string) v2.ResourceRule {
+		r := v2.ResourceRule{Name: name}
+		r.APIVersion = apiVersion
+		r.Kind = kind
+		return r
+	}
+
+	rules, err := compileRules("test", []v2.ResourceRule{
+		rule("", "", "web"),
+		rule("apps/v1", "Deployment", "web"),
+		rule("v1", "ConfigMap", "web"),
+		rule("", "Deployment", ".*"),
+		rule("apps/v1", "", "db"),
+		{Cluster: "other"},
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for match := range rules.matching(newResource("apps/v1", "Deployment", "web", "1")) {
+		names = append(names, match.rule.APIVersion+"/"+match.rule.Kind+"/"+match.rule.Name)
+	}
+
+	assert.DeepEqual(t, names, []string{"//web", "apps/v1/Deployment/web", "/Deployment/.*"})
+}
+
+func TestRuleExpressionFailure(t *testing.T) {
+	rules, err := compileRules("test", []v2.ResourceRule{{Expression: `object.spec.replicas > 1`}})
+	require.NoError(t, err)
+
+	rule := &rules.rules[0]
+	assert.Equal(t, rule.matches(newResource("v1", "ConfigMap", "web", "1")), false)
+	assert.Equal(t, rule.evalFailed.Load(), true)
+
+	// later failures are not reported as error again but still do not match
+	assert.Equal(t, rule.matches(newResource("v1", "ConfigMap", "db", "2")), false)
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+// builtinSeverities are the severities of zombie kinds which are not assigned by a rule or policy.
+// All other kinds are considered a warning.
+var builtinSeverities = map[schema.GroupKind]v2.Severity{
+	{Kind: "Secret"}:         v2.SeverityCritical,
+	{Kind: "ServiceAccount"}: v2.SeverityCritical,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                       v2.SeverityCritical,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                v2.SeverityCritical,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:                              v2.SeverityCritical,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:                       v2.SeverityCritical,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   v2.SeverityCritical,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: v2.SeverityCritical,
+	{Kind: "ConfigMap"}: v2.SeverityInfo,
+}
+
+// Severity returns the severity assigned to a zombie.
+func Severity(res unstructured.Unstructured) v2.Severity {
+	return v2.Severity(res.GetAnnotations()[AnnotationSeverity])
+}
+
+// AssignRuleSeverity returns a FilterFunc which assigns the severity of the first matching rule to resources,
+// overriding the severity of an inclusion. It fails if any pattern or expression of the rules applying to the
+// cluster does not compile.
+func AssignRuleSeverity(cluster string, severities []v2.ResourceRule) (FilterFunc, error) {
+	rules, err := compileRules(cluster, severities)
+	if err != nil {
+		return nil, fmt.Errorf("invalid severity rule: %w", err)
+	}
+
+	return func(res unstructured.Unstructured, _ klog.Logger) bool {
+		for rule := range rules.matching(res) {
+			setAnnotation(&res, AnnotationSeverity, string(rule.rule.Severity))
+			break
+		}
+
+		return false
+	}, nil
+}
+
+// AssignSeverity returns a FilterFunc which assigns the builtin severity of the kind to resources
+// which have not been assigned a severity by a rule. Reported helm releases are a warning regardless of
+// their storage secret. It never filters a resource.
+func AssignSeverity() FilterFunc {
+	return func(res unstructured.Unstructured, _ klog.Logger) bool {
+		if Severity(res) != "" {
+			return false
+		}
+
+		severity, ok := builtinSeverities[res.GroupVersionKind().GroupKind()]
+		if _, release := res.GetAnnotations()[AnnotationHelmRelease]; release || !ok {
+			severity = v2.SeverityWarning
+		}
+
+		setAnnotation(&res, AnnotationSeverity, string(severity))
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestAssignSeverity(t *testing.T) {
+	assigned := newResource("v1", "ConfigMap", "important", "5")
+	assigned.SetAnnotations(map[string]string{AnnotationSeverity: string(v2.SeverityCritical)})
+
+	tests := []struct {
+		res      unstructured.Unstructured
+		severity v2.Severity
+	}{
+		{res: newResource("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "admin", "1"), severity: v2.SeverityCritical},
+		{res: newResource("v1", "Secret", "token", "2"), severity: v2.SeverityCritical},
+		{res: newResource("v1", "ConfigMap", "leftover", "3"), severity: v2.SeverityInfo},
+		{res: newResource("apps/v1", "Deployment", "web", "4"), severity: v2.SeverityWarning},
+		{res: assigned, severity: v2.SeverityCritical},
+	}
+
+	filter := AssignSeverity()
+	for _, test := range tests {
+		t.Run(test.res.GetKind()+"/"+test.res.GetName(), func(t *testing.T) {
+			assert.Equal(t, filter(test.res, klog.Background()), false)
+			assert.Equal(t, Severity(test.res), test.severity)
+		})
+	}
+}
+
+func TestRuleSeverity(t *testing.T) {
+	rules := []v2.ResourceRule{
+		{Name: "web", Severity: v2.SeverityInfo},
+		{Name: "web|db", Severity: v2.SeverityCritical},
+		{Name: "cache"},
+	}
+
+	severities, err := AssignRuleSeverity("", rules[:2])
+	require.NoError(t, err)
+	inclusions, err := IgnoreIfNotIncluded("", rules)
+	require.NoError(t, err)
+
+	web := newResource("v1", "ConfigMap", "web", "1")
+	assert.Equal(t, severities(web, klog.Background()), false)
+	assert.Equal(t, Severity(web), v2.SeverityInfo)
+
+	cache := newResource("v1", "ConfigMap", "cache", "2")
+	assert.Equal(t, severities(cache, klog.Background()), false)
+	assert.Equal(t, Severity(cache), v2.Severity(""))
+
+	db := newResource("v1", "ConfigMap", "db", "3")
+	assert.Equal(t, inclusions(db, klog.Background()), false)
+	assert.Equal(t, Severity(db), v2.SeverityCritical)
+
+	// severity rules override the severity of an inclusion
+	overridden := newResource("v1", "ConfigMap", "web", "4")
+	criticalInclusions, err := IgnoreIfNotIncluded("", []v2.ResourceRule{{Name: "web", Severity: v2.SeverityCritical}})
+	require.NoError(t, err)
+	assert.Equal(t, criticalInclusions(overridden, klog.Background()), false)
+	assert.Equal(t, Severity(overridden), v2.SeverityCritical)
+	assert.Equal(t, severities(overridden, klog.Background()), false)
+	assert.Equal(t, Severity(overridden), v2.SeverityInfo)
+}
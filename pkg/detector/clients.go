@@ -1,18 +1,13 @@
 package detector
 
 import (
-	"context"
-	"fmt"
-
 	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
-	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 func getDiscoveryClient(kubeconfigArgs *genericclioptions.ConfigFlags) (*discovery.DiscoveryClient, error) {
@@ -69,71 +64,34 @@ func getRestClient(kubeconfigArgs *genericclioptions.ConfigFlags) (*rest.RESTCli
 	return client, nil
 }
 
-func getClusterClientsFromConfig(
-	ctx context.Context,
-	gitopsClient dynamic.Interface,
-	namespace string,
-	specStr any,
-) (string, clusterClients, error) {
-	spec := ksapi.KustomizationSpec{}
-	b, err := json.Marshal(specStr)
-	if err != nil {
-		return "", clusterClients{}, err
-	}
-
-	err = json.Unmarshal(b, &spec)
-	if err != nil {
-		return "", clusterClients{}, err
-	}
-
-	secret, err := loadKubeconfigSecret(ctx, gitopsClient, namespace, spec.KubeConfig.SecretRef.Name)
-	if err != nil {
-		return "", clusterClients{}, err
-	}
-
-	var kubeConfig []byte
-	switch {
-	case spec.KubeConfig.SecretRef.Key != "":
-		key := spec.KubeConfig.SecretRef.Key
-		kubeConfig = secret.Data[key]
-		if kubeConfig == nil {
-			return "", clusterClients{}, fmt.Errorf(
-				"KubeConfig secret '%s' does not contain a '%s' key with a kubeconfig",
-				spec.KubeConfig.SecretRef.Name,
-				key,
-			)
-		}
-	case secret.Data["value"] != nil:
-		kubeConfig = secret.Data["value"]
-	case secret.Data["value.yaml"] != nil:
-		kubeConfig = secret.Data["value.yaml"]
-	default:
-		return "", clusterClients{}, fmt.Errorf(
-			"KubeConfig secret '%s' does not contain a 'value' nor 'value.yaml' key with a kubeconfig",
-			spec.KubeConfig.SecretRef.Name,
-		)
-	}
-	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
-	if err != nil {
-		return "", clusterClients{}, err
-	}
-
+func newClusterClients(restConfig *rest.Config) (clusterClients, error) {
 	restConfig.WarningHandler = rest.NoWarnings{}
 
 	dynClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
-		return "", clusterClients{}, err
+		return clusterClients{}, err
 	}
 
 	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
 	if err != nil {
-		return "", clusterClients{}, err
+		return clusterClients{}, err
 	}
 
-	cfg, err := clientcmd.Load(kubeConfig)
+	return clusterClients{config: restConfig, dynamic: dynClient, discovery: discoveryClient}, nil
+}
+
+// preferredVersion returns the preferred version of an api group, an empty version if the group is not served.
+func preferredVersion(discoveryClient discovery.DiscoveryInterface, group string) (string, error) {
+	groups, err := discoveryClient.ServerGroups()
 	if err != nil {
-		return "", clusterClients{}, err
+		return "", err
+	}
+
+	for _, apiGroup := range groups.Groups {
+		if apiGroup.Name == group {
+			return apiGroup.PreferredVersion.Version, nil
+		}
 	}
 
-	return cfg.Contexts[cfg.CurrentContext].Cluster, clusterClients{dynamic: dynClient, discovery: discoveryClient}, nil
+	return "", nil
 }
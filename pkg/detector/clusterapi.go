@@ -0,0 +1,73 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+const (
+	clusterAPIGroup = "cluster.x-k8s.io"
+	// clusterAPIProvisioned is the phase of Cluster API clusters whose control plane is reachable.
+	clusterAPIProvisioned = "Provisioned"
+	// clusterAPIKubeConfigKey is the key of the kubeconfig within the <name>-kubeconfig secret of Cluster API clusters.
+	clusterAPIKubeConfigKey = "value"
+)
+
+// listClusterAPISources returns the kubeConfig secrets (<name>-kubeconfig) of all Cluster API clusters.
+// Clusters which are being deleted or are not provisioned yet are skipped, there are none if Cluster API is not installed.
+func listClusterAPISources(
+	ctx context.Context,
+	discoveryClient discovery.DiscoveryInterface,
+	client dynamic.Interface,
+) ([]kubeConfigSource, error) {
+	version, err := preferredVersion(discoveryClient, clusterAPIGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		klog.V(1).Infof("api group %s is not served, no cluster api clusters to discover", clusterAPIGroup)
+		return nil, nil
+	}
+
+	clusters, err := listResources(ctx, client.Resource(schema.GroupVersionResource{
+		Group:    clusterAPIGroup,
+		Version:  version,
+		Resource: "clusters",
+	}), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster api clusters: %w", err)
+	}
+
+	var sources []kubeConfigSource
+	for _, cluster := range clusters {
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+		if cluster.GetDeletionTimestamp() != nil || (phase != "" && phase != clusterAPIProvisioned) {
+			klog.V(1).Infof("skipping cluster api cluster %s/%s in phase %s", cluster.GetNamespace(), cluster.GetName(), phase)
+			continue
+		}
+
+		// the secret is resolved like a kubeConfig reference, which dedupes it with references of Kustomizations and HelmReleases
+		source, err := newKubeConfigSource(cluster.GetNamespace(), &meta.KubeConfigReference{
+			SecretRef: &meta.SecretKeyReference{Name: cluster.GetName() + "-kubeconfig", Key: clusterAPIKubeConfigKey},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+
+	return sources, nil
+}
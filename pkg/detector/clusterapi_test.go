@@ -0,0 +1,150 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+var clusterAPIClustersGVR = schema.GroupVersionResource{Group: clusterAPIGroup, Version: "v1beta1", Resource: "clusters"}
+
+func newClusterAPICluster(namespace, name, phase string, deleting bool) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": clusterAPIClustersGVR.GroupVersion().String(),
+		"kind":       "Cluster",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}}
+
+	if phase != "" {
+		cluster.Object["status"] = map[string]interface{}{"phase": phase}
+	}
+
+	if deleting {
+		now := metav1.Now()
+		cluster.SetDeletionTimestamp(&now)
+	}
+
+	return cluster
+}
+
+func newClusterAPIDiscovery(groupVersions ...string) *discoveryfake.FakeDiscovery {
+	fake := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	for _, groupVersion := range groupVersions {
+		fake.Resources = append(fake.Resources, &metav1.APIResourceList{GroupVersion: groupVersion})
+	}
+
+	return fake
+}
+
+func newFailingDiscovery(err error) *discoveryfake.FakeDiscovery {
+	fake := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	fake.AddReactor("get", "group", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	})
+
+	return fake
+}
+
+func TestListClusterAPISources(t *testing.T) {
+	tests := []struct {
+		name        string
+		discovery   *discoveryfake.FakeDiscovery
+		clusters    []runtime.Object
+		expected    []kubeConfigSource
+		expectedErr string
+	}{
+		{
+			name:      "cluster api is not served",
+			discovery: newClusterAPIDiscovery("apps/v1"),
+		},
+		{
+			name:        "discovery fails",
+			discovery:   newFailingDiscovery(errors.New("connection refused")),
+			expectedErr: "connection refused",
+		},
+		{
+			name:      "provisioned clusters",
+			discovery: newClusterAPIDiscovery("apps/v1", clusterAPIClustersGVR.GroupVersion().String()),
+			clusters: []runtime.Object{
+				newClusterAPICluster("capi", "prod", clusterAPIProvisioned, false),
+				newClusterAPICluster("capi", "staging", "", false),
+				newClusterAPICluster("capi", "provisioning", "Provisioning", false),
+				newClusterAPICluster("capi", "deleting", clusterAPIProvisioned, true),
+			},
+			expected: []kubeConfigSource{
+				{kind: "Secret", namespace: "capi", name: "prod-kubeconfig"},
+				{kind: "Secret", namespace: "capi", name: "staging-kubeconfig"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{clusterAPIClustersGVR: "ClusterList"}, test.clusters...)
+
+			sources, err := listClusterAPISources(context.TODO(), test.discovery, client)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sources, test.expected, cmp.AllowUnexported(kubeConfigSource{}))
+		})
+	}
+}
+
+func TestClusterAPISourcesDedupe(t *testing.T) {
+	d := &Detector{conf: &v2.Config{}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterAPIClustersGVR: "ClusterList"},
+		newClusterAPICluster("capi", "prod", clusterAPIProvisioned, false),
+		newKubeConfigSecret("capi", "prod-kubeconfig", ""),
+	)
+
+	sources, err := listClusterAPISources(
+		context.TODO(),
+		newClusterAPIDiscovery(clusterAPIClustersGVR.GroupVersion().String()),
+		client,
+	)
+	assert.NilError(t, err)
+
+	// flux references the secret of the cluster api cluster by its key
+	kustomizations := []ksapi.Kustomization{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "capi"},
+			Spec: ksapi.KustomizationSpec{KubeConfig: &meta.KubeConfigReference{
+				SecretRef: &meta.SecretKeyReference{Name: "prod-kubeconfig", Key: "value"},
+			}},
+		},
+	}
+
+	clients, clusters := d.getClustersClientsFromKustomizationsAndHelmReleases(
+		context.TODO(),
+		client,
+		kustomizations,
+		nil,
+		sources,
+	)
+
+	assert.Equal(t, len(clients), 1)
+	assert.DeepEqual(t, clusters, map[kubeConfigSource]string{
+		{kind: "Secret", namespace: "capi", name: "prod-kubeconfig"}: "capi/prod-kubeconfig",
+	}, cmp.AllowUnexported(kubeConfigSource{}))
+	assert.Equal(t, len(d.Errors()), 0)
+}
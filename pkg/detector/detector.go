@@ -1,27 +1,33 @@
+// Package detector provides functionality for detecting zombie resources in Kubernetes clusters.
 package detector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	helmapi "github.com/fluxcd/helm-controller/api/v2"
 	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/pkg/apis/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	k8sget "k8s.io/kubectl/pkg/cmd/get"
+	"k8s.io/utils/ptr"
 
-	gitopszombiesv1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
 	"github.com/raffis/gitops-zombies/pkg/collector"
 )
 
@@ -37,24 +43,72 @@ type clusterDetectionResult struct {
 }
 
 type clusterClients struct {
+	config    *rest.Config
 	dynamic   dynamic.Interface
 	discovery *discovery.DiscoveryClient
 }
 
+// gitopsResources are the HelmReleases and Kustomizations of the flux cluster alongside the clusters they target.
+type gitopsResources struct {
+	helmReleases   []helmapi.HelmRelease
+	kustomizations []ksapi.Kustomization
+	// inventoryOwners are the flux operator ResourceSets and FluxInstances
+	inventoryOwners []unstructured.Unstructured
+	clusters        map[string]clusterClients
+	// sources are the names of the remote clusters by their kubeConfig source
+	sources map[kubeConfigSource]string
+}
+
+// targetCluster returns the name of the cluster a kubeConfig reference of a resource in the namespace targets.
+// It returns false if the cluster can not be connected to.
+func (r *gitopsResources) targetCluster(namespace string, ref *meta.KubeConfigReference) (string, bool) {
+	if ref == nil {
+		return fluxClusterName, true
+	}
+
+	source, err := newKubeConfigSource(namespace, ref)
+	if err != nil {
+		return "", false
+	}
+
+	cluster, ok := r.sources[source]
+	return cluster, ok
+}
+
+// ClusterError is the error of a cluster which could not be scanned or was scanned incompletely.
+type ClusterError struct {
+	Cluster string
+	Err     error
+}
+
+func (e ClusterError) Error() string {
+	return fmt.Sprintf("[%s] %v", e.Cluster, e.Err)
+}
+
 // Detector owns detector materials.
 type Detector struct {
 	gitopsDynClient        dynamic.Interface
+	clusterRestConfig      *rest.Config
 	clusterDiscoveryClient *discovery.DiscoveryClient
 	clusterDynClient       dynamic.Interface
 	gitopsRestClient       *rest.RESTClient
 	kubeconfigArgs         *genericclioptions.ConfigFlags
 	printFlags             *k8sget.PrintFlags
-	conf                   *gitopszombiesv1.Config
+	conf                   *v2.Config
+	references             map[string]*collector.ReferenceIndex
+	tenants                []TenantSummary
+	clusterErrors          []ClusterError
+	clusters               []string
+	// scanClusters are the names of the clusters by the key of their scans
+	scanClusters map[string]string
+	context      string
+	policy       *collector.Policy
+	mu           sync.Mutex
 }
 
 // New creates a new detection object.
 func New(
-	conf *gitopszombiesv1.Config,
+	conf *v2.Config,
 	kubeconfigArgs *genericclioptions.ConfigFlags,
 	printFlags *k8sget.PrintFlags,
 ) (*Detector, error) {
@@ -63,6 +117,11 @@ func New(
 		return nil, err
 	}
 
+	clusterRestConfig, err := kubeconfigArgs.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	clusterDiscoveryClient, err := getDiscoveryClient(kubeconfigArgs)
 	if err != nil {
 		return nil, err
@@ -78,14 +137,26 @@ func New(
 		return nil, err
 	}
 
+	var policy *collector.Policy
+	if conf.Policy != "" {
+		policy, err = collector.LoadPolicy(context.TODO(), conf.Policy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Detector{
 		gitopsDynClient:        gitopsDynClient,
+		clusterRestConfig:      clusterRestConfig,
 		clusterDiscoveryClient: clusterDiscoveryClient,
 		clusterDynClient:       clusterDynClient,
 		gitopsRestClient:       gitopsRestClient,
 		conf:                   conf,
 		kubeconfigArgs:         kubeconfigArgs,
 		printFlags:             printFlags,
+		references:             make(map[string]*collector.ReferenceIndex),
+		scanClusters:           make(map[string]string),
+		policy:                 policy,
 	}, nil
 }
 
@@ -94,40 +165,69 @@ func (d *Detector) DetectZombies() (resourceCount int, zombies map[string][]unst
 	zombies = make(map[string][]unstructured.Unstructured)
 	ch := make(chan clusterDetectionResult)
 
-	helmReleases, kustomizations, clustersConfigs, err := d.listGitopsResources()
+	resources, err := d.listGitopsResources()
 	if err != nil {
 		return 0, nil, err
 	}
 
-	var wg sync.WaitGroup
-	clustersConfigs[fluxClusterName] = clusterClients{dynamic: d.clusterDynClient, discovery: d.clusterDiscoveryClient}
+	// ownership lookups of all clusters share the index
+	index := collector.NewFluxIndex(resources.helmReleases, resources.kustomizations)
+	index.AddInventoryOwners(resources.inventoryOwners)
+	for _, hr := range resources.helmReleases {
+		// releases of clusters which are not scanned can not be matched anyway
+		if cluster, ok := resources.targetCluster(hr.Namespace, hr.Spec.KubeConfig); ok {
+			index.AddHelmStorage(cluster, hr)
+		}
+	}
+
+	resources.clusters[fluxClusterName] = clusterClients{
+		config:    d.clusterRestConfig,
+		dynamic:   d.clusterDynClient,
+		discovery: d.clusterDiscoveryClient,
+	}
 
-	for cluster := range clustersConfigs {
-		if d.conf.ExcludeClusters != nil && slices.Contains(d.conf.ExcludeClusters, cluster) {
-			klog.Infof("[%s] excluding from zombie detection", cluster)
+	d.mu.Lock()
+	d.clusters = slices.Sorted(maps.Keys(resources.clusters))
+	d.mu.Unlock()
+
+	var scans []clusterScan
+	if ptr.Deref(d.conf.ImpersonateServiceAccounts, false) {
+		scans, err = tenantScans(resources, *d.kubeconfigArgs.Namespace)
+		if err != nil {
+			return 0, nil, err
+		}
+	} else {
+		for cluster, clients := range resources.clusters {
+			scans = append(scans, clusterScan{cluster: cluster, clients: clients})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, scan := range scans {
+		scan.context = d.context
+		if d.conf.ExcludeClusters != nil && slices.Contains(d.conf.ExcludeClusters, scan.cluster) {
+			klog.Infof("[%s] excluding from zombie detection", scan.key())
 			continue
 		}
 
+		d.mu.Lock()
+		d.scanClusters[scan.key()] = scan.cluster
+		d.mu.Unlock()
+
 		wg.Add(1)
-		go func(cluster string) {
+		go func(scan clusterScan) {
 			defer wg.Done()
 
-			clusterResourceCount, clusterZombies, err := d.detectZombiesOnCluster(
-				cluster,
-				helmReleases,
-				kustomizations,
-				clustersConfigs[cluster].dynamic,
-				clustersConfigs[cluster].discovery,
-			)
+			clusterResourceCount, clusterZombies, err := d.detectZombiesOnCluster(scan, index)
 			if err != nil {
-				klog.Errorf("[%s] could not detect zombies on: %v", cluster, err)
+				d.addError(scan.key(), fmt.Errorf("could not detect zombies: %w", err))
 			}
 			ch <- clusterDetectionResult{
-				cluster:       cluster,
+				cluster:       scan.key(),
 				resourceCount: clusterResourceCount,
 				zombies:       clusterZombies,
 			}
-		}(cluster)
+		}(scan)
 	}
 
 	go func() {
@@ -143,24 +243,87 @@ func (d *Detector) DetectZombies() (resourceCount int, zombies map[string][]unst
 	return resourceCount, zombies, nil
 }
 
+// SetContext groups the zombies of all clusters by the kubeconfig context the detector scans.
+func (d *Detector) SetContext(name string) {
+	d.context = name
+}
+
+// Tenants returns the summaries of the clusters scanned impersonating tenants.
+func (d *Detector) Tenants() []TenantSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tenants := slices.Clone(d.tenants)
+	slices.SortFunc(tenants, func(a, b TenantSummary) int {
+		return strings.Compare(a.Cluster, b.Cluster)
+	})
+
+	return tenants
+}
+
+// Clusters returns the names of the clusters discovered.
+func (d *Detector) Clusters() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.clusters)
+}
+
+// Errors returns the errors of the clusters which could not be scanned or were scanned incompletely.
+func (d *Detector) Errors() []ClusterError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clusterErrors := slices.Clone(d.clusterErrors)
+	slices.SortStableFunc(clusterErrors, func(a, b ClusterError) int {
+		return strings.Compare(a.Cluster, b.Cluster)
+	})
+
+	return clusterErrors
+}
+
+// addError reports the error of a cluster, the remaining clusters are still scanned.
+func (d *Detector) addError(cluster string, err error) {
+	klog.Errorf("[%s] %v", cluster, err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clusterErrors = append(d.clusterErrors, ClusterError{Cluster: cluster, Err: err})
+}
+
+// clusterKey returns the key of a cluster of the context the detector scans.
+func (d *Detector) clusterKey(cluster string) string {
+	return clusterScan{context: d.context, cluster: cluster}.key()
+}
+
 // PrintZombies prints all workload not managed by gitops.
 func (d *Detector) PrintZombies(allZombies map[string][]unstructured.Unstructured) error {
+	if ptr.Deref(d.conf.Tree, false) {
+		return d.printTree(os.Stdout, allZombies)
+	}
+
 	p, err := d.printFlags.ToPrinter()
 	if err != nil {
 		return err
 	}
 
-	for clusterName, zombies := range allZombies {
-		for _, zombie := range zombies {
+	for _, clusterName := range slices.Sorted(maps.Keys(allZombies)) {
+		for _, zombie := range d.orderZombies(allZombies[clusterName]) {
 			if *d.printFlags.OutputFormat == "" {
 				ok := zombie.GetObjectKind().GroupVersionKind()
-				fmt.Printf("[%s] %s: %s.%s\n", clusterName, ok.String(), zombie.GetName(), zombie.GetNamespace())
-			} else {
-				z := zombie
-				err := p.PrintObj(&z, os.Stdout)
-				if err != nil {
-					return err
-				}
+				fmt.Printf(
+					"[%s] %s: %s.%s%s\n",
+					clusterName,
+					ok.String(),
+					zombie.GetName(),
+					zombie.GetNamespace(),
+					describeZombie(zombie),
+				)
+				continue
+			}
+
+			if err := p.PrintObj(d.structuredZombie(zombie), os.Stdout); err != nil {
+				return err
 			}
 		}
 	}
@@ -168,33 +331,361 @@ func (d *Detector) PrintZombies(allZombies map[string][]unstructured.Unstructure
 	return nil
 }
 
+// StructuredZombies returns the zombies as printed by structured output formats, each annotated with the kubeconfig
+// context and the cluster it was detected on to tell the zombies of multiple contexts apart.
+func (d *Detector) StructuredZombies(allZombies map[string][]unstructured.Unstructured) []unstructured.Unstructured {
+	d.mu.Lock()
+	scanClusters := maps.Clone(d.scanClusters)
+	d.mu.Unlock()
+
+	var objs []unstructured.Unstructured
+	for _, key := range slices.Sorted(maps.Keys(allZombies)) {
+		for _, zombie := range d.orderZombies(allZombies[key]) {
+			obj := d.structuredZombie(zombie)
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+
+			annotations[collector.AnnotationContext] = d.context
+			annotations[collector.AnnotationCluster] = scanClusters[key]
+			obj.SetAnnotations(annotations)
+			objs = append(objs, *obj)
+		}
+	}
+
+	return objs
+}
+
+// orderZombies sorts the zombies of a cluster and groups them by their root owner.
+func (d *Detector) orderZombies(zombies []unstructured.Unstructured) []unstructured.Unstructured {
+	sortZombies(zombies, d.conf.SortBy)
+	return groupByRootOwner(zombies)
+}
+
+// structuredZombie returns a zombie as printed by structured output formats.
+// The findings are only added on request, zombies are printed as they are otherwise.
+func (d *Detector) structuredZombie(zombie unstructured.Unstructured) *unstructured.Unstructured {
+	obj := zombie.DeepCopy()
+	if ptr.Deref(d.conf.Annotate, false) {
+		collector.AnnotateLastModification(obj)
+	} else {
+		collector.RemoveFindings(obj)
+	}
+
+	return obj
+}
+
+func describeZombie(zombie unstructured.Unstructured) string {
+	var details []string
+	if severity := collector.Severity(zombie); severity != "" {
+		details = append(details, "severity: "+string(severity))
+	}
+
+	if created := zombie.GetCreationTimestamp(); !created.IsZero() {
+		details = append(details, "age: "+duration.HumanDuration(time.Since(created.Time)))
+	}
+
+	if entry, ok := collector.LastModification(zombie); ok {
+		details = append(details, fmt.Sprintf(
+			"last modified: %s/%s %s ago",
+			entry.Manager,
+			entry.Operation,
+			duration.HumanDuration(time.Since(entry.Time.Time)),
+		))
+	}
+
+	if managers, ok := zombie.GetAnnotations()[collector.AnnotationManuallyModifiedBy]; ok {
+		details = append(details, "manually modified by: "+managers)
+	}
+
+	if root, ok := zombie.GetAnnotations()[collector.AnnotationRootOwner]; ok {
+		details = append(details, "owned by zombie: "+root)
+	}
+
+	if owner, ok := zombie.GetAnnotations()[collector.AnnotationDanglingOwner]; ok {
+		details = append(details, "dangling owner: "+owner)
+	}
+
+	if refs, ok := zombie.GetAnnotations()[collector.AnnotationReferencedBy]; ok {
+		details = append(details, "referenced by: "+refs)
+	}
+
+	if release, ok := zombie.GetAnnotations()[collector.AnnotationHelmRelease]; ok {
+		details = append(details, fmt.Sprintf(
+			"helm release: %s (chart %s), resources: %s",
+			release,
+			zombie.GetAnnotations()[collector.AnnotationHelmChart],
+			zombie.GetAnnotations()[collector.AnnotationHelmReleaseResources],
+		))
+	}
+
+	if messages, ok := zombie.GetAnnotations()[collector.AnnotationMessages]; ok {
+		details = append(details, "messages: "+messages)
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+
+	return " (" + strings.Join(details, ", ") + ")"
+}
+
+func sortZombies(zombies []unstructured.Unstructured, sortBy string) {
+	if sortBy != v2.SortByAge {
+		return
+	}
+
+	// oldest first, same as kubectl --sort-by=.metadata.creationTimestamp
+	slices.SortStableFunc(zombies, func(a, b unstructured.Unstructured) int {
+		return a.GetCreationTimestamp().Compare(b.GetCreationTimestamp().Time)
+	})
+}
+
+func (d *Detector) referenceIndex(clusterName string) *collector.ReferenceIndex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.references[clusterName]
+}
+
+// groupByRootOwner moves zombies owned by another zombie right after their root owner.
+func groupByRootOwner(zombies []unstructured.Unstructured) []unstructured.Unstructured {
+	children := make(map[string][]unstructured.Unstructured)
+	var roots []unstructured.Unstructured
+	for _, zombie := range zombies {
+		if root, ok := zombie.GetAnnotations()[collector.AnnotationRootOwner]; ok {
+			children[root] = append(children[root], zombie)
+		} else {
+			roots = append(roots, zombie)
+		}
+	}
+
+	grouped := make([]unstructured.Unstructured, 0, len(zombies))
+	for _, root := range roots {
+		ref := collector.ObjectReference(root)
+		grouped = append(grouped, root)
+		grouped = append(grouped, children[ref]...)
+		delete(children, ref)
+	}
+
+	// root owners are always discovered, this is just a safety net to never drop any zombie
+	for _, ref := range slices.Sorted(maps.Keys(children)) {
+		grouped = append(grouped, children[ref]...)
+	}
+
+	return grouped
+}
+
 func (d *Detector) detectZombiesOnCluster(
-	clusterName string,
-	helmReleases []helmapi.HelmRelease,
-	kustomizations []ksapi.Kustomization,
-	clusterDynClient dynamic.Interface,
-	clusterDiscoveryClient *discovery.DiscoveryClient,
+	scan clusterScan,
+	index *collector.FluxIndex,
 ) (int, []unstructured.Unstructured, error) {
-	var (
-		resourceCount int
-		zombies       []unstructured.Unstructured
-	)
+	var zombies []unstructured.Unstructured
+	clusterName := scan.cluster
+	conf := d.conf.ForCluster(clusterName)
+
+	resources, listedKinds, err := d.listClusterResources(conf, scan)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var references *collector.ReferenceIndex
+	if ptr.Deref(conf.Tree, false) || ptr.Deref(conf.References, false) {
+		references = collector.NewReferenceIndex(resources)
+		d.mu.Lock()
+		d.references[scan.key()] = references
+		d.mu.Unlock()
+	}
+
+	logger := klog.NewKlogr().WithValues("cluster", scan.key())
+
+	var releases *collector.HelmReleases
+	if ptr.Deref(conf.DetectHelmReleases, false) {
+		releases = collector.NewHelmReleases(clusterName, resources, index, logger)
+	}
+
+	graph := collector.NewOwnerGraph(resources, listedKinds)
+	ownership := ownershipFilters(conf, index)
+	filters, err := clusterFilters(conf, clusterName, resources, listedKinds, index, releases, graph, ownership, d.policy)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if releases != nil {
+		// the resources of a release are only collapsed into its storage secret if the secret is not managed,
+		// the config and the policy apply to the storage secret as the zombie representing the release
+		releases.Resolve(append([]collector.FilterFunc{collector.IgnoreOwnedByManagedResource(graph)}, ownership...), logger)
+	}
+
+	discover := collector.NewDiscovery(logger, filters...)
+
+	var owned, unowned unstructured.UnstructuredList
+	for _, res := range resources {
+		if len(res.GetOwnerReferences()) > 0 {
+			owned.Items = append(owned.Items, res)
+		} else {
+			unowned.Items = append(unowned.Items, res)
+		}
+	}
+
+	ch := make(chan unstructured.Unstructured)
+	var wgConsumer sync.WaitGroup
+	wgConsumer.Add(1)
+	go func() {
+		defer wgConsumer.Done()
+		for res := range ch {
+			// zombies are collected in stream mode as well to evaluate the fail conditions
+			zombies = append(zombies, res)
+			if !ptr.Deref(d.conf.NoStream, false) {
+				_ = d.PrintZombies(map[string][]unstructured.Unstructured{scan.key(): {res}})
+			}
+		}
+	}()
+
+	// owned resources are only reported if their root owner is a zombie, hence the roots are discovered first
+	roots := make(chan unstructured.Unstructured)
+	var rootsErr error
+	go func() {
+		defer close(roots)
+		rootsErr = discover.Discover(context.TODO(), &unowned, roots)
+	}()
 
-	discover := collector.NewDiscovery(
-		klog.NewKlogr().WithValues("cluster", clusterName),
-		collector.IgnoreOwnedResource(),
+	for res := range roots {
+		graph.MarkZombie(res)
+		ch <- res
+	}
+
+	if rootsErr == nil {
+		err = discover.Discover(context.TODO(), &owned, ch)
+	}
+
+	close(ch)
+	wgConsumer.Wait()
+
+	if references != nil {
+		references.AnnotateReferencedBy(zombies)
+	}
+
+	if scan.tenant != nil {
+		summary := TenantSummary{Cluster: scan.key(), Resources: len(resources), Zombies: len(zombies)}
+		for _, res := range resources {
+			if scan.tenant.owns(res) {
+				summary.Owned++
+			}
+		}
+
+		d.mu.Lock()
+		d.tenants = append(d.tenants, summary)
+		d.mu.Unlock()
+	}
+
+	return len(resources), zombies, errors.Join(rootsErr, err)
+}
+
+// ownershipFilters returns the filters which ignore resources managed by flux.
+func ownershipFilters(conf *v2.Config, index *collector.FluxIndex) []collector.FilterFunc {
+	filters := []collector.FilterFunc{
+		collector.IgnoreIfHelmReleaseFound(index),
+		collector.IgnoreIfKustomizationFound(index),
+		collector.IgnoreIfResourceSetFound(index),
+		collector.IgnoreIfFluxInstanceFound(index),
+	}
+
+	if ptr.Deref(conf.FluxSSAOwnership, false) {
+		filters = append(filters, collector.IgnoreIfAppliedByFlux())
+	}
+
+	if ptr.Deref(conf.DetectDrift, false) {
+		for i, filter := range filters {
+			filters[i] = collector.ReportManuallyModified(conf.TrustedFieldManagers, filter)
+		}
+	}
+
+	return filters
+}
+
+func clusterFilters(
+	conf *v2.Config,
+	clusterName string,
+	resources []unstructured.Unstructured,
+	listedKinds []schema.GroupKind,
+	index *collector.FluxIndex,
+	releases *collector.HelmReleases,
+	graph *collector.OwnerGraph,
+	ownership []collector.FilterFunc,
+	policy *collector.Policy,
+) ([]collector.FilterFunc, error) {
+	ignoreNamespaces, err := collector.IgnoreNamespaces(conf.IncludeNamespaces, conf.ExcludeNamespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreIfNotIncluded, err := collector.IgnoreIfNotIncluded(clusterName, conf.IncludeResources)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreRuleExclusions, err := collector.IgnoreRuleExclusions(clusterName, conf.ExcludeResources)
+	if err != nil {
+		return nil, err
+	}
+
+	assignRuleSeverity, err := collector.AssignRuleSeverity(clusterName, conf.Severities)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreWellKnownObjects, err := collector.IgnoreWellKnownObjects(conf.IgnorePresets, resources, listedKinds)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []collector.FilterFunc{
+		collector.IgnoreOwnedByManagedResource(graph),
 		collector.IgnoreServiceAccountSecret(),
+	}
+
+	// unmanaged helm releases are reported by their storage secret which must not be ignored as helm secret
+	if releases != nil {
+		filters = append(filters, collector.ReportHelmReleases(releases))
+	}
+
+	filters = append(filters,
 		collector.IgnoreHelmSecret(),
-		collector.IgnoreIfHelmReleaseFound(helmReleases),
-		collector.IgnoreIfKustomizationFound(kustomizations),
-		collector.IgnoreRuleExclusions(clusterName, d.conf.ExcludeResources),
+		ignoreWellKnownObjects,
+		collector.IgnoreYoungerThan(conf.MinAge.Duration),
+		ignoreNamespaces,
+		ignoreIfNotIncluded,
 	)
+	filters = append(filters, ownership...)
+	filters = append(filters,
+		ignoreRuleExclusions,
+		assignRuleSeverity,
+		collector.AssignSeverity(),
+	)
+
+	// the policy is evaluated last for the remaining zombies only
+	if policy != nil {
+		filters = append(filters, collector.IgnoreByPolicy(policy, clusterName, index))
+	}
+
+	return filters, nil
+}
+
+// listClusterResources lists all resources of all supported apis on a cluster.
+// It returns the kinds which were listed completely alongside the resources.
+func (d *Detector) listClusterResources(
+	conf *v2.Config,
+	scan clusterScan,
+) ([]unstructured.Unstructured, []schema.GroupKind, error) {
+	clusterName := scan.key()
+	clusterDynClient, clusterDiscoveryClient := scan.clients.dynamic, scan.clients.discovery
 
 	var list []*metav1.APIResourceList
 	klog.V(1).Infof("[%s] discover all api groups and resources", clusterName)
 	list, err := listServerGroupsAndResources(clusterDiscoveryClient)
 	if err != nil {
-		return 0, nil, err
+		return nil, nil, err
 	}
 	for _, g := range list {
 		klog.V(1).Infof("[%s] found group %v with the following resources", clusterName, g.GroupVersion)
@@ -207,65 +698,111 @@ func (d *Detector) detectZombiesOnCluster(
 		}
 	}
 
-	ch := make(chan unstructured.Unstructured)
-	var wgProducer, wgConsumer sync.WaitGroup
+	if unresolved := unresolvedKinds(conf, list); len(unresolved) > 0 {
+		klog.Warningf("[%s] no api resources found for kinds %s", clusterName, strings.Join(unresolved, ", "))
+	}
+
+	var scope namespaceScope
+	if scan.tenant != nil {
+		// tenants are usually not allowed to list resources of all namespaces
+		scope = namespaceScope{restricted: true, namespaces: scan.tenant.namespaces, clusterScoped: ptr.Deref(conf.IncludeClusterScoped, false)}
+	} else {
+		scope, err = resolveNamespaceScope(context.TODO(), clusterDynClient, conf, *d.kubeconfigArgs.Namespace)
+		if err != nil {
+			klog.V(1).Infof("[%s] could not resolve namespaces, filtering them after listing: %v", clusterName, err)
+		}
+	}
+
+	var (
+		resources   []unstructured.Unstructured
+		listedKinds []schema.GroupKind
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+	)
+
 	for _, group := range list {
 		klog.V(1).Infof("[%s] discover resource group %#v", clusterName, group.GroupVersion)
 		gv, err := schema.ParseGroupVersion(group.GroupVersion)
 		if err != nil {
-			return 0, nil, err
+			return nil, nil, err
 		}
 
 		for _, resource := range group.APIResources {
 			klog.V(1).
 				Infof("[%s] discover resource %#v.%#v.%#v", clusterName, resource.Name, resource.Group, resource.Version)
 
-			gvr, err := d.validateResource(*d.kubeconfigArgs.Namespace, gv, resource)
+			if !resource.Namespaced && !scope.clusterScoped {
+				klog.V(1).Infof(
+					"[%s] skipping cluster scoped resource %#v.%#v.%#v, namespaced scope was requested",
+					clusterName,
+					resource.Name,
+					resource.Group,
+					resource.Version,
+				)
+				continue
+			}
+
+			selected, explicit := selectKind(conf, gv, resource)
+			if !selected {
+				klog.V(1).Infof("[%s] skipping resource %#v.%#v.%#v, kind was not requested", clusterName, resource.Name, resource.Group, resource.Version)
+				continue
+			}
+
+			gvr, err := validateResource(conf, gv, resource, explicit)
 			if err != nil {
 				klog.V(1).Infof("[%s] %v", clusterName, err.Error())
 				continue
 			}
 
-			resAPI := clusterDynClient.Resource(*gvr).Namespace(*d.kubeconfigArgs.Namespace)
+			opts := metav1.ListOptions{LabelSelector: getLabelSelector(conf, *gvr)}
+			namespaces := []string{metav1.NamespaceAll}
+			if resource.Namespaced {
+				if scope.restricted {
+					namespaces = scope.namespaces
+				} else {
+					opts.FieldSelector = scope.fieldSelector
+				}
+			}
+
+			gk := gv.WithKind(resource.Kind).GroupKind()
 
-			wgProducer.Add(1)
+			wg.Add(1)
+			go func(gvr schema.GroupVersionResource) {
+				defer wg.Done()
 
-			go func(resAPI dynamic.ResourceInterface) {
-				defer wgProducer.Done()
+				var items []unstructured.Unstructured
+				for _, namespace := range namespaces {
+					nsItems, err := listResources(context.TODO(), clusterDynClient.Resource(gvr).Namespace(namespace), opts)
+					if err != nil {
+						klog.V(1).Infof("[%s] could not handle resource: %v", clusterName, err)
+						return
+					}
 
-				count, err := handleResource(context.TODO(), discover, resAPI, ch, d.getLabelSelector())
-				if err != nil {
-					klog.V(1).Infof("[%s] could not handle resource: %v", clusterName, err)
+					items = append(items, nsItems...)
 				}
-				resourceCount += count
-			}(resAPI)
-		}
-	}
 
-	wgConsumer.Add(1)
-	go func() {
-		defer wgConsumer.Done()
-		for res := range ch {
-			if d.conf.NoStream {
-				zombies = append(zombies, res)
-			} else {
-				_ = d.PrintZombies(map[string][]unstructured.Unstructured{clusterName: {res}})
-			}
+				mu.Lock()
+				defer mu.Unlock()
+				resources = append(resources, items...)
+
+				// owners might not match a user defined label selector, so kinds are only complete without one
+				if !hasUserLabelSelector(conf, gvr) {
+					listedKinds = append(listedKinds, gk)
+				}
+			}(*gvr)
 		}
-	}()
+	}
 
-	wgProducer.Wait()
-	close(ch)
-	wgConsumer.Wait()
+	wg.Wait()
 
-	return resourceCount, zombies, nil
+	return resources, listedKinds, nil
 }
 
-func (d *Detector) listGitopsResources() ([]helmapi.HelmRelease, []ksapi.Kustomization, map[string]clusterClients, error) {
+func (d *Detector) listGitopsResources() (*gitopsResources, error) {
 	klog.V(1).Infof("discover all helmreleases")
-	helmReleases, err := listHelmReleases(context.TODO(), d.gitopsDynClient, d.getLabelSelector())
+	helmReleases, err := listHelmReleases(context.TODO(), d.gitopsDynClient, getLabelSelector(d.conf, helmReleasesGVR))
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get helmreleases: %w", err)
+		return nil, fmt.Errorf("failed to get helmreleases: %w", err)
 	}
 	for _, h := range helmReleases {
 		klog.V(1).Infof(" |_ %s.%s", h.GetName(), h.GetNamespace())
@@ -274,118 +811,177 @@ func (d *Detector) listGitopsResources() ([]helmapi.HelmRelease, []ksapi.Kustomi
 	klog.V(1).Infof("discover all kustomizations")
 	kustomizations, err := listKustomizations(context.TODO(), d.gitopsRestClient)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get kustomizations: %w", err)
+		return nil, fmt.Errorf("failed to get kustomizations: %w", err)
 	}
 
 	for _, k := range kustomizations {
 		klog.V(1).Infof(" |_ %s.%s", k.GetName(), k.GetNamespace())
 	}
 
+	klog.V(1).Infof("discover all resourcesets and fluxinstances")
+	inventoryOwners, err := listInventoryOwners(context.TODO(), d.clusterDiscoveryClient, d.gitopsDynClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resourcesets and fluxinstances: %w", err)
+	}
+
+	for _, o := range inventoryOwners {
+		klog.V(1).Infof(" |_ %s %s.%s", o.GetKind(), o.GetName(), o.GetNamespace())
+	}
+
+	var clusterAPISources []kubeConfigSource
+	if ptr.Deref(d.conf.DiscoverClusterAPI, false) {
+		klog.V(1).Infof("discover all cluster api clusters")
+		clusterAPISources, err = listClusterAPISources(context.TODO(), d.clusterDiscoveryClient, d.gitopsDynClient)
+		if err != nil {
+			d.addError(d.clusterKey(fluxClusterName), fmt.Errorf("failed to discover cluster api clusters: %w", err))
+		}
+	}
+
 	klog.V(1).Infof("discover all managed clustersClients")
-	clustersClients, err := d.getClustersClientsFromKustomizationsAndHelmReleases(
+	clustersClients, sources := d.getClustersClientsFromKustomizationsAndHelmReleases(
 		context.TODO(),
 		d.gitopsDynClient,
 		kustomizations,
 		helmReleases,
+		clusterAPISources,
 	)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get managed clustersClients: %w", err)
-	}
 
 	for clusterName := range clustersClients {
 		klog.V(1).Infof(" |_ %s", clusterName)
 	}
 
-	return helmReleases, kustomizations, clustersClients, nil
+	return &gitopsResources{
+		helmReleases:    helmReleases,
+		kustomizations:  kustomizations,
+		inventoryOwners: inventoryOwners,
+		clusters:        clustersClients,
+		sources:         sources,
+	}, nil
 }
 
+// getClustersClientsFromKustomizationsAndHelmReleases builds the clients of all remote clusters referenced by
+// kubeConfigs and the additional sources alongside the cluster names by kubeConfig source.
+// Clusters which can not be reached are reported as errors and skipped instead of failing the whole run.
 func (d *Detector) getClustersClientsFromKustomizationsAndHelmReleases(
 	ctx context.Context,
 	gitopsClient dynamic.Interface,
 	kustomizations []ksapi.Kustomization,
 	helmReleases []helmapi.HelmRelease,
-) (map[string]clusterClients, error) {
-	resourcesWithSecrets := map[string]*unstructured.Unstructured{}
-	clients := make(map[string]clusterClients)
+	additional []kubeConfigSource,
+) (map[string]clusterClients, map[kubeConfigSource]string) {
+	var sources []kubeConfigSource
+	addSource := func(kind, namespace, name string, ref *meta.KubeConfigReference) {
+		if ref == nil {
+			return
+		}
 
-	for _, ks := range kustomizations {
-		if ks.Spec.KubeConfig != nil {
-			key := fmt.Sprintf("%s/%s", ks.Namespace, ks.Spec.KubeConfig.SecretRef.Name)
-			if _, ok := resourcesWithSecrets[key]; !ok {
-				ksu, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&ks)
-				if err != nil {
-					return nil, err
-				}
-				resourcesWithSecrets[key] = &unstructured.Unstructured{Object: ksu}
-			}
+		source, err := newKubeConfigSource(namespace, ref)
+		if err != nil {
+			d.addError(d.clusterKey(fmt.Sprintf("%s %s/%s", kind, namespace, name)), err)
+			return
+		}
+
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
 		}
 	}
 
+	for _, ks := range kustomizations {
+		addSource(ksapi.KustomizationKind, ks.Namespace, ks.Name, ks.Spec.KubeConfig)
+	}
+
 	for _, hr := range helmReleases {
-		if hr.Spec.KubeConfig != nil {
-			key := fmt.Sprintf("%s/%s", hr.Namespace, hr.Spec.KubeConfig.SecretRef.Name)
-			if _, ok := resourcesWithSecrets[key]; !ok {
-				hru, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&hr)
-				if err != nil {
-					return nil, err
-				}
-				resourcesWithSecrets[key] = &unstructured.Unstructured{Object: hru}
-			}
+		addSource(helmapi.HelmReleaseKind, hr.Namespace, hr.Name, hr.Spec.KubeConfig)
+	}
+
+	for _, source := range additional {
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
 		}
 	}
 
-	for _, r := range resourcesWithSecrets {
-		clusterName, clusterClts, err := getClusterClientsFromConfig(
-			ctx,
-			gitopsClient,
-			r.GetNamespace(),
-			r.Object["spec"],
-		)
+	// the first source claiming a cluster name wins collisions
+	slices.SortFunc(sources, func(a, b kubeConfigSource) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	clients := make(map[string]clusterClients)
+	clusters := make(map[kubeConfigSource]string)
+	claimedBy := make(map[string]kubeConfigSource)
+	for _, source := range sources {
+		clusterName, clusterClts, err := clusterClientsForSource(ctx, gitopsClient, source, d.conf)
 		if err != nil {
-			return nil, err
+			d.addError(d.clusterKey(source.String()), fmt.Errorf("could not connect to cluster: %w", err))
+			continue
+		}
+
+		if err := claimCluster(claimedBy, source, clusterName); err != nil {
+			d.addError(d.clusterKey(source.String()), err)
+			continue
 		}
 
 		clients[clusterName] = clusterClts
+		clusters[source] = clusterName
 	}
 
-	return clients, nil
+	return clients, clusters
 }
 
-func (d *Detector) getLabelSelector() string {
-	selector := ""
-	if !d.conf.IncludeAll {
-		selector = defaultLabelSelector
+// claimCluster claims the name of the cluster a kubeConfig source points to.
+// The name of the flux cluster is reserved and a name claimed by another source collides.
+func claimCluster(claimedBy map[string]kubeConfigSource, source kubeConfigSource, clusterName string) error {
+	if clusterName == fluxClusterName {
+		return fmt.Errorf("cluster name %q is reserved for the flux cluster, skipping cluster", clusterName)
 	}
 
-	if d.conf.LabelSelector != "" {
-		selector = strings.Join(append(strings.Split(selector, ","), strings.Split(d.conf.LabelSelector, ",")...), ",")
+	if other, ok := claimedBy[clusterName]; ok {
+		return fmt.Errorf("cluster name %q collides with %s, skipping cluster", clusterName, other)
 	}
 
-	return selector
+	claimedBy[clusterName] = source
+	return nil
 }
 
-func (d *Detector) validateResource(
-	ns string,
+func getLabelSelector(conf *v2.Config, gvr schema.GroupVersionResource) string {
+	var selectors []string
+	if !ptr.Deref(conf.IncludeAll, false) {
+		selectors = append(selectors, defaultLabelSelector)
+	}
+
+	if conf.LabelSelector != "" {
+		selectors = append(selectors, conf.LabelSelector)
+	}
+
+	for _, selector := range conf.LabelSelectors {
+		if selector.Matches(gvr) {
+			selectors = append(selectors, selector.Selector)
+		}
+	}
+
+	return strings.Join(selectors, ",")
+}
+
+func hasUserLabelSelector(conf *v2.Config, gvr schema.GroupVersionResource) bool {
+	return conf.LabelSelector != "" || slices.ContainsFunc(conf.LabelSelectors, func(selector v2.ResourceLabelSelector) bool {
+		return selector.Matches(gvr)
+	})
+}
+
+func validateResource(
+	conf *v2.Config,
 	gv schema.GroupVersion,
 	resource metav1.APIResource,
+	explicit bool,
 ) (*schema.GroupVersionResource, error) {
-	if ns != "" && !resource.Namespaced {
-		return nil, fmt.Errorf(
-			"skipping cluster scoped resource %#v.%#v.%#v, namespaced scope was requested",
-			resource.Name,
-			resource.Group,
-			resource.Version,
-		)
-	}
-
 	gvr := schema.GroupVersionResource{
 		Group:    gv.Group,
 		Version:  gv.Version,
 		Resource: resource.Name,
 	}
 
-	if !d.conf.IncludeAll {
-		if slices.Contains(getBlacklist(), gvr) {
+	// resources requested explicitly are included even if they are considered dynamic
+	if !ptr.Deref(conf.IncludeAll, false) && !explicit {
+		if conf.IsBlacklisted(gvr) {
 			return nil, fmt.Errorf("skipping blacklisted api resource %v/%v.%v", gvr.Group, gvr.Version, gvr.Resource)
 		}
 	}
@@ -396,20 +992,3 @@ func (d *Detector) validateResource(
 
 	return &gvr, nil
 }
-
-func handleResource(
-	ctx context.Context,
-	discover collector.Interface,
-	resAPI dynamic.ResourceInterface,
-	ch chan unstructured.Unstructured,
-	labelSelector string,
-) (int, error) {
-	list, err := resAPI.List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
-	if err != nil {
-		return 0, err
-	}
-
-	return len(list.Items), discover.Discover(ctx, list, ch)
-}
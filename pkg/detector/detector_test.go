@@ -0,0 +1,172 @@
+package detector
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+func TestClusterErrors(t *testing.T) {
+	d := &Detector{conf: &v2.Config{}}
+	d.SetContext("prod")
+
+	kustomizations := []ksapi.Kustomization{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "tenant"},
+			Spec: ksapi.KustomizationSpec{KubeConfig: &meta.KubeConfigReference{
+				SecretRef: &meta.SecretKeyReference{Name: "remote-kubeconfig"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "tenant"},
+			Spec:       ksapi.KustomizationSpec{KubeConfig: &meta.KubeConfigReference{}},
+		},
+	}
+
+	clients, sources := d.getClustersClientsFromKustomizationsAndHelmReleases(
+		context.TODO(),
+		dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		kustomizations,
+		nil,
+		nil,
+	)
+	assert.Equal(t, len(clients), 0)
+	assert.Equal(t, len(sources), 0)
+
+	clusterErrors := d.Errors()
+	assert.Equal(t, len(clusterErrors), 2)
+	assert.Equal(t, clusterErrors[0].Error(), "[prod | Kustomization tenant/invalid] kubeConfig references neither a secret nor a config map")
+	assert.Equal(t, clusterErrors[1].Cluster, "prod | secret tenant/remote-kubeconfig")
+	assert.ErrorContains(t, clusterErrors[1].Err, "could not connect to cluster")
+}
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example.com
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: secret
+`
+
+func newKubeConfigSecret(namespace, name, clusterName string) *unstructured.Unstructured {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"data":       map[string]interface{}{"value": base64.StdEncoding.EncodeToString([]byte(testKubeConfig))},
+	}}
+
+	if clusterName != "" {
+		secret.SetAnnotations(map[string]string{clusterNameAnnotation: clusterName})
+	}
+
+	return secret
+}
+
+func TestClusterNameCollisions(t *testing.T) {
+	d := &Detector{conf: &v2.Config{}}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newKubeConfigSecret("apps", "a-kubeconfig", "prod"),
+		newKubeConfigSecret("apps", "b-kubeconfig", "prod"),
+		newKubeConfigSecret("apps", "c-kubeconfig", fluxClusterName),
+		newKubeConfigSecret("apps", "d-kubeconfig", ""),
+	)
+
+	var kustomizations []ksapi.Kustomization
+	for _, name := range []string{"d-kubeconfig", "c-kubeconfig", "b-kubeconfig", "a-kubeconfig"} {
+		kustomizations = append(kustomizations, ksapi.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+			Spec: ksapi.KustomizationSpec{KubeConfig: &meta.KubeConfigReference{
+				SecretRef: &meta.SecretKeyReference{Name: name},
+			}},
+		})
+	}
+
+	// the default key and no key reference the same cluster
+	kustomizations[0].Spec.KubeConfig.SecretRef.Key = "value"
+	additional := []kubeConfigSource{{kind: "Secret", namespace: "apps", name: "d-kubeconfig"}}
+
+	clients, sources := d.getClustersClientsFromKustomizationsAndHelmReleases(
+		context.TODO(),
+		client,
+		kustomizations,
+		nil,
+		additional,
+	)
+
+	assert.Equal(t, len(clients), 2)
+	assert.DeepEqual(t, sources, map[kubeConfigSource]string{
+		{kind: "Secret", namespace: "apps", name: "a-kubeconfig"}: "prod",
+		{kind: "Secret", namespace: "apps", name: "d-kubeconfig"}: "apps/d-kubeconfig",
+	}, cmp.AllowUnexported(kubeConfigSource{}))
+
+	clusterErrors := d.Errors()
+	assert.Equal(t, len(clusterErrors), 2)
+	assert.Equal(t, clusterErrors[0].Error(),
+		`[secret apps/b-kubeconfig] cluster name "prod" collides with secret apps/a-kubeconfig, skipping cluster`)
+	assert.Equal(t, clusterErrors[1].Error(),
+		`[secret apps/c-kubeconfig] cluster name "self" is reserved for the flux cluster, skipping cluster`)
+}
+
+func TestStructuredZombies(t *testing.T) {
+	zombie := func(name string, annotations map[string]string) unstructured.Unstructured {
+		res := unstructured.Unstructured{}
+		res.SetAPIVersion("v1")
+		res.SetKind("ConfigMap")
+		res.SetName(name)
+		res.SetNamespace("default")
+		res.SetAnnotations(annotations)
+		return res
+	}
+
+	d := &Detector{conf: &v2.Config{}, scanClusters: make(map[string]string)}
+	d.SetContext("prod")
+	for _, scan := range []clusterScan{
+		{context: "prod", cluster: "self"},
+		{context: "prod", cluster: "apps/prod-kubeconfig"},
+	} {
+		d.scanClusters[scan.key()] = scan.cluster
+	}
+
+	objs := d.StructuredZombies(map[string][]unstructured.Unstructured{
+		"prod | self":                 {zombie("debug", map[string]string{collector.AnnotationSeverity: "info", "owner": "jane"})},
+		"prod | apps/prod-kubeconfig": {zombie("manual", nil)},
+	})
+
+	assert.Equal(t, len(objs), 2)
+	assert.Equal(t, objs[0].GetName(), "manual")
+	assert.DeepEqual(t, objs[0].GetAnnotations(), map[string]string{
+		collector.AnnotationContext: "prod",
+		collector.AnnotationCluster: "apps/prod-kubeconfig",
+	})
+
+	// findings are removed unless requested
+	assert.Equal(t, objs[1].GetName(), "debug")
+	assert.DeepEqual(t, objs[1].GetAnnotations(), map[string]string{
+		"owner":                     "jane",
+		collector.AnnotationContext: "prod",
+		collector.AnnotationCluster: "self",
+	})
+}
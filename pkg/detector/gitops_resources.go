@@ -5,22 +5,46 @@ import (
 
 	helmapi "github.com/fluxcd/helm-controller/api/v2"
 	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/raffis/gitops-zombies/pkg/collector"
 )
 
+var helmReleasesGVR = schema.GroupVersionResource{
+	Group:    "helm.toolkit.fluxcd.io",
+	Version:  "v2",
+	Resource: "helmreleases",
+}
+
+// fluxOperatorGroup is the api group of the flux operator.
+const fluxOperatorGroup = "fluxcd.controlplane.io"
+
+var resourceSetsGVR = schema.GroupVersionResource{
+	Group:    fluxOperatorGroup,
+	Version:  "v1",
+	Resource: "resourcesets",
+}
+
+var fluxInstancesGVR = schema.GroupVersionResource{
+	Group:    fluxOperatorGroup,
+	Version:  "v1",
+	Resource: "fluxinstances",
+}
+
 func listResources(
 	ctx context.Context,
 	resAPI dynamic.ResourceInterface,
-	labelSelector string,
+	opts metav1.ListOptions,
 ) (items []unstructured.Unstructured, err error) {
-	list, err := resAPI.List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
+	list, err := resAPI.List(ctx, opts)
 	if err != nil {
 		return items, err
 	}
@@ -34,12 +58,7 @@ func listHelmReleases(
 	labelSelector string,
 ) ([]helmapi.HelmRelease, error) {
 	helmReleases := []helmapi.HelmRelease{}
-	list, err := listResources(ctx,
-		gitopsClient.Resource(schema.GroupVersionResource{
-			Group:    "helm.toolkit.fluxcd.io",
-			Version:  "v2",
-			Resource: "helmreleases",
-		}), labelSelector)
+	list, err := listResources(ctx, gitopsClient.Resource(helmReleasesGVR), metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +75,56 @@ func listHelmReleases(
 	return helmReleases, nil
 }
 
+// listInventoryOwners lists the flux operator ResourceSets and FluxInstances which keep an inventory of the objects
+// they apply. Clusters without the flux operator do not serve these apis. Users which may not list them, like tenants,
+// are warned as the objects applied by them can not be told apart from zombies.
+func listInventoryOwners(
+	ctx context.Context,
+	discoveryClient discovery.DiscoveryInterface,
+	gitopsClient dynamic.Interface,
+) ([]unstructured.Unstructured, error) {
+	version, err := preferredVersion(discoveryClient, fluxOperatorGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		klog.V(1).Infof("api group %s is not served, no resourcesets and fluxinstances to discover", fluxOperatorGroup)
+		return nil, nil
+	}
+
+	var owners []unstructured.Unstructured
+	for _, gvr := range []schema.GroupVersionResource{resourceSetsGVR, fluxInstancesGVR} {
+		list, err := listResources(ctx, gitopsClient.Resource(gvr), metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+
+		if apierrors.IsForbidden(err) {
+			klog.Warningf("not allowed to list %s, objects applied by them may be reported as zombies: %v", gvr.GroupResource(), err)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, element := range list {
+			// the kind is not guaranteed to be set on the items of a list
+			if element.GetKind() == "" {
+				element.SetKind(collector.ResourceSetKind)
+				if gvr == fluxInstancesGVR {
+					element.SetKind(collector.FluxInstanceKind)
+				}
+			}
+
+			owners = append(owners, element)
+		}
+	}
+
+	return owners, nil
+}
+
 func listKustomizations(ctx context.Context, client *rest.RESTClient) ([]ksapi.Kustomization, error) {
 	ks := &ksapi.KustomizationList{}
 
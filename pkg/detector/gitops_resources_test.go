@@ -0,0 +1,103 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+func newInventoryOwner(gvr schema.GroupVersionResource, kind, namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}}
+}
+
+func TestListInventoryOwners(t *testing.T) {
+	fluxOperator := resourceSetsGVR.GroupVersion().String()
+
+	tests := []struct {
+		name          string
+		discovery     *discoveryfake.FakeDiscovery
+		owners        []runtime.Object
+		forbidden     string
+		expected      []string
+		expectedErr   string
+		expectedLists int
+	}{
+		{
+			name:      "flux operator is not served",
+			discovery: newClusterAPIDiscovery("apps/v1"),
+		},
+		{
+			name:        "discovery fails",
+			discovery:   newFailingDiscovery(errors.New("connection refused")),
+			expectedErr: "connection refused",
+		},
+		{
+			name:      "resourcesets and fluxinstances",
+			discovery: newClusterAPIDiscovery(fluxOperator),
+			owners: []runtime.Object{
+				newInventoryOwner(resourceSetsGVR, collector.ResourceSetKind, "apps", "podinfo"),
+				newInventoryOwner(fluxInstancesGVR, "FluxInstance", "flux-system", "flux"),
+			},
+			expected:      []string{"ResourceSet/apps/podinfo", "FluxInstance/flux-system/flux"},
+			expectedLists: 2,
+		},
+		{
+			name:      "resourcesets are forbidden",
+			discovery: newClusterAPIDiscovery(fluxOperator),
+			owners: []runtime.Object{
+				newInventoryOwner(resourceSetsGVR, collector.ResourceSetKind, "apps", "podinfo"),
+				newInventoryOwner(fluxInstancesGVR, "FluxInstance", "flux-system", "flux"),
+			},
+			forbidden:     resourceSetsGVR.Resource,
+			expected:      []string{"FluxInstance/flux-system/flux"},
+			expectedLists: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{
+					resourceSetsGVR:  "ResourceSetList",
+					fluxInstancesGVR: "FluxInstanceList",
+				}, test.owners...)
+
+			if test.forbidden != "" {
+				client.PrependReactor("list", test.forbidden, func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(resourceSetsGVR.GroupResource(), "", errors.New("tenant"))
+				})
+			}
+
+			owners, err := listInventoryOwners(context.TODO(), test.discovery, client)
+			if test.expectedErr != "" {
+				assert.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, len(client.Actions()), test.expectedLists)
+
+			var names []string
+			for _, owner := range owners {
+				names = append(names, owner.GetKind()+"/"+owner.GetNamespace()+"/"+owner.GetName())
+			}
+
+			assert.DeepEqual(t, names, test.expected)
+		})
+	}
+}
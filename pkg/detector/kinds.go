@@ -0,0 +1,41 @@
+package detector
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+// selectKind returns whether an api resource is scanned according to the requested and excluded kinds and
+// whether it has been requested explicitly.
+func selectKind(conf *v2.Config, gv schema.GroupVersion, resource metav1.APIResource) (selected, explicit bool) {
+	matches := func(kind string) bool {
+		return v2.MatchesKind(kind, gv, resource)
+	}
+
+	if slices.ContainsFunc(conf.ExcludeKinds, matches) {
+		return false, false
+	}
+
+	if len(conf.Kinds) == 0 {
+		return true, false
+	}
+
+	explicit = slices.ContainsFunc(conf.Kinds, matches)
+	return explicit, explicit
+}
+
+// unresolvedKinds returns the requested kinds which none of the api resources of a cluster matches.
+func unresolvedKinds(conf *v2.Config, lists []*metav1.APIResourceList) []string {
+	var unresolved []string
+	for _, kind := range conf.Kinds {
+		if !v2.ServesKind(lists, kind) {
+			unresolved = append(unresolved, kind)
+		}
+	}
+
+	return unresolved
+}
@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestSelectKind(t *testing.T) {
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	deployments := metav1.APIResource{Name: "deployments", SingularName: "deployment", Kind: "Deployment", ShortNames: []string{"deploy"}}
+
+	tests := []struct {
+		name             string
+		conf             v2.Config
+		expectedSelected bool
+		expectedExplicit bool
+	}{
+		{
+			name:             "no kinds",
+			expectedSelected: true,
+		},
+		{
+			name:             "requested by kind",
+			conf:             v2.Config{Kinds: []string{"Deployment"}},
+			expectedSelected: true,
+			expectedExplicit: true,
+		},
+		{
+			name:             "requested by kind.group",
+			conf:             v2.Config{Kinds: []string{"deploy.apps"}},
+			expectedSelected: true,
+			expectedExplicit: true,
+		},
+		{
+			name:             "requested by resource.version.group",
+			conf:             v2.Config{Kinds: []string{"deployments.v1.apps"}},
+			expectedSelected: true,
+			expectedExplicit: true,
+		},
+		{
+			name: "requested by another group",
+			conf: v2.Config{Kinds: []string{"deployments.v1.extensions"}},
+		},
+		{
+			name: "other kinds requested",
+			conf: v2.Config{Kinds: []string{"StatefulSet"}},
+		},
+		{
+			name: "excluded by kind",
+			conf: v2.Config{ExcludeKinds: []string{"deployment"}},
+		},
+		{
+			name: "excluded by kind.group",
+			conf: v2.Config{ExcludeKinds: []string{"Deployment.apps"}},
+		},
+		{
+			name: "exclusion wins over request",
+			conf: v2.Config{Kinds: []string{"Deployment"}, ExcludeKinds: []string{"deployments.v1.apps"}},
+		},
+		{
+			name:             "excluded by another group",
+			conf:             v2.Config{ExcludeKinds: []string{"deployment.extensions"}},
+			expectedSelected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, explicit := selectKind(&test.conf, apps, deployments)
+			assert.Equal(t, selected, test.expectedSelected)
+			assert.Equal(t, explicit, test.expectedExplicit)
+		})
+	}
+}
+
+func TestUnresolvedKinds(t *testing.T) {
+	lists := []*metav1.APIResourceList{
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", SingularName: "deployment", Kind: "Deployment"}}},
+	}
+
+	conf := &v2.Config{Kinds: []string{"deployment.apps", "Widget", "deployments.v2.apps"}}
+	assert.DeepEqual(t, unresolvedKinds(conf, lists), []string{"Widget", "deployments.v2.apps"})
+	assert.Assert(t, len(unresolvedKinds(&v2.Config{}, lists)) == 0)
+}
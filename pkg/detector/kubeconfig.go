@@ -0,0 +1,487 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+const (
+	kubeConfigProviderAWS     = "aws"
+	kubeConfigProviderAzure   = "azure"
+	kubeConfigProviderGCP     = "gcp"
+	kubeConfigProviderGeneric = "generic"
+
+	// defaultKubeConfigSecretKey is the key of kubeConfig secrets read if no key is set.
+	defaultKubeConfigSecretKey = "value"
+
+	// clusterNameAnnotation overrides the name of the cluster a kubeConfig secret or config map points to.
+	clusterNameAnnotation = "gitops-zombies.io/cluster-name"
+
+	execAPIVersion = "client.authentication.k8s.io/v1beta1"
+	// azureKubernetesServerID is the application id of the AKS AAD server all AKS clusters share.
+	azureKubernetesServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+)
+
+// execPlugin restricts the arguments and environment of a builtin credential plugin.
+type execPlugin struct {
+	// subcommand are the leading arguments the plugin must be called with
+	subcommand []string
+	// flags are the flags the plugin may be called with, true if the flag takes a value
+	flags map[string]bool
+	// env are the environment variables which may be set
+	env []string
+}
+
+// awsEnv are the environment variables selecting the local aws credentials and region.
+var awsEnv = []string{"AWS_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_STS_REGIONAL_ENDPOINTS"}
+
+// builtinExecCommands restrict the arguments and environment of well-known credential plugins once they are allowed.
+// Commands are matched exactly, a plugin referenced by a path is not a builtin one.
+var builtinExecCommands = map[string]execPlugin{
+	"aws": {
+		subcommand: []string{"eks", "get-token"},
+		flags: map[string]bool{
+			"--cluster-name":      true,
+			"--cluster-id":        true,
+			"--region":            true,
+			"--role-arn":          true,
+			"--role-session-name": true,
+			"--output":            true,
+			"--profile":           true,
+		},
+		env: awsEnv,
+	},
+	"aws-iam-authenticator": {
+		subcommand: []string{"token"},
+		flags: map[string]bool{
+			"-i":           true,
+			"--cluster-id": true,
+			"-r":           true,
+			"--role":       true,
+			"--region":     true,
+		},
+		env: awsEnv,
+	},
+	"gke-gcloud-auth-plugin": {
+		flags: map[string]bool{
+			"--use_application_default_credentials": false,
+		},
+	},
+	"kubelogin": {
+		subcommand: []string{"get-token"},
+		flags: map[string]bool{
+			"-l":            true,
+			"--login":       true,
+			"--server-id":   true,
+			"--client-id":   true,
+			"--tenant-id":   true,
+			"-e":            true,
+			"--environment": true,
+		},
+	},
+}
+
+var serviceAccountsGVR = schema.GroupVersionResource{
+	Group:    "",
+	Version:  "v1",
+	Resource: "serviceaccounts",
+}
+
+var configMapsGVR = schema.GroupVersionResource{
+	Group:    "",
+	Version:  "v1",
+	Resource: "configmaps",
+}
+
+// kubeConfigSource is a kubeConfig reference of a Kustomization or HelmRelease resolved to its namespace.
+type kubeConfigSource struct {
+	kind      string
+	namespace string
+	name      string
+	key       string
+}
+
+// newKubeConfigSource returns the source of a kubeConfig reference.
+// The default key of secrets (value) is normalised to an empty key, references with and without it point to the same cluster.
+func newKubeConfigSource(namespace string, ref *meta.KubeConfigReference) (kubeConfigSource, error) {
+	switch {
+	case ref.SecretRef != nil:
+		key := ref.SecretRef.Key
+		if key == defaultKubeConfigSecretKey {
+			key = ""
+		}
+
+		return kubeConfigSource{kind: "Secret", namespace: namespace, name: ref.SecretRef.Name, key: key}, nil
+	case ref.ConfigMapRef != nil:
+		return kubeConfigSource{kind: "ConfigMap", namespace: namespace, name: ref.ConfigMapRef.Name}, nil
+	default:
+		return kubeConfigSource{}, errors.New("kubeConfig references neither a secret nor a config map")
+	}
+}
+
+func (s kubeConfigSource) String() string {
+	return strings.ToLower(s.kind) + " " + s.identity()
+}
+
+// identity returns the stable identity of the cluster a kubeConfig source points to (namespace/name[/key]).
+func (s kubeConfigSource) identity() string {
+	ref := s.namespace + "/" + s.name
+	if s.key != "" {
+		ref += "/" + s.key
+	}
+
+	return ref
+}
+
+// clusterName returns the name of the cluster a kubeConfig source points to, which is the identity of the source
+// unless it is overridden by the cluster name annotation of the secret or config map.
+func (s kubeConfigSource) clusterName(annotations map[string]string) string {
+	if name := annotations[clusterNameAnnotation]; name != "" {
+		return name
+	}
+
+	return s.identity()
+}
+
+// clusterClientsForSource builds the clients of the remote cluster a kubeConfig source points to.
+func clusterClientsForSource(
+	ctx context.Context,
+	gitopsClient dynamic.Interface,
+	source kubeConfigSource,
+	conf *v2.Config,
+) (string, clusterClients, error) {
+	var (
+		clusterName string
+		restConfig  *rest.Config
+		err         error
+	)
+
+	switch source.kind {
+	case "Secret":
+		clusterName, restConfig, err = restConfigFromSecret(ctx, gitopsClient, source, conf.AllowedExecCommands)
+	case "ConfigMap":
+		clusterName, restConfig, err = restConfigFromConfigMap(ctx, gitopsClient, source, conf)
+	default:
+		err = fmt.Errorf("unsupported kubeConfig source %s", source.kind)
+	}
+
+	if err != nil {
+		return "", clusterClients{}, err
+	}
+
+	clients, err := newClusterClients(restConfig)
+	return clusterName, clients, err
+}
+
+func restConfigFromSecret(
+	ctx context.Context,
+	gitopsClient dynamic.Interface,
+	source kubeConfigSource,
+	allowedExecCommands []string,
+) (string, *rest.Config, error) {
+	secret, err := loadKubeconfigSecret(ctx, gitopsClient, source.namespace, source.name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var kubeConfig []byte
+	switch {
+	case source.key != "":
+		kubeConfig = secret.Data[source.key]
+		if kubeConfig == nil {
+			return "", nil, fmt.Errorf(
+				"KubeConfig secret '%s' does not contain a '%s' key with a kubeconfig",
+				source.name,
+				source.key,
+			)
+		}
+	case secret.Data[defaultKubeConfigSecretKey] != nil:
+		kubeConfig = secret.Data[defaultKubeConfigSecretKey]
+	case secret.Data["value.yaml"] != nil:
+		kubeConfig = secret.Data["value.yaml"]
+	default:
+		return "", nil, fmt.Errorf(
+			"KubeConfig secret '%s' does not contain a 'value' nor 'value.yaml' key with a kubeconfig",
+			source.name,
+		)
+	}
+
+	clusterName := source.clusterName(secret.GetAnnotations())
+	cfg, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := validateKubeConfig(cfg, allowedExecCommands); err != nil {
+		return "", nil, err
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*cfg, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return clusterName, restConfig, nil
+}
+
+// restConfigFromConfigMap builds the rest config of a workload identity kubeConfig.
+// The generic provider authenticates by a token of the configured ServiceAccount like the flux controllers do,
+// requesting it is a write on the flux cluster and has to be enabled by requestServiceAccountTokens.
+// Cloud providers authenticate by the credential plugin of the provider using the local credentials of the
+// user instead of the workload identity of the controller, as looking up clusters at the cloud provider is not
+// supported the address and the ca certificate of the cluster are required.
+func restConfigFromConfigMap(
+	ctx context.Context,
+	gitopsClient dynamic.Interface,
+	source kubeConfigSource,
+	conf *v2.Config,
+) (string, *rest.Config, error) {
+	element, err := gitopsClient.Resource(configMapsGVR).
+		Namespace(source.namespace).
+		Get(ctx, source.name, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var configMap corev1.ConfigMap
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(element.UnstructuredContent(), &configMap); err != nil {
+		return "", nil, err
+	}
+
+	data := configMap.Data
+	provider := data[meta.KubeConfigKeyProvider]
+	address := data[meta.KubeConfigKeyAddress]
+	if address == "" {
+		return "", nil, fmt.Errorf(
+			"KubeConfig config map '%s' does not contain an '%s', looking up %s clusters is not supported",
+			source.name,
+			meta.KubeConfigKeyAddress,
+			provider,
+		)
+	}
+
+	restConfig := &rest.Config{
+		Host:            address,
+		TLSClientConfig: rest.TLSClientConfig{CAData: []byte(data[meta.KubeConfigKeyCACert])},
+	}
+
+	clusterName := source.clusterName(configMap.GetAnnotations())
+
+	if provider == kubeConfigProviderGeneric {
+		if !ptr.Deref(conf.RequestServiceAccountTokens, false) {
+			return "", nil, fmt.Errorf(
+				"the %s provider requests a token of service account %s/%s, see requestServiceAccountTokens",
+				kubeConfigProviderGeneric,
+				source.namespace,
+				data[meta.KubeConfigKeyServiceAccountName],
+			)
+		}
+
+		token, err := requestServiceAccountToken(ctx, gitopsClient, source.namespace, data)
+		if err != nil {
+			return "", nil, err
+		}
+
+		restConfig.BearerToken = token
+		return clusterName, restConfig, nil
+	}
+
+	if data[meta.KubeConfigKeyCACert] == "" {
+		return "", nil, fmt.Errorf(
+			"KubeConfig config map '%s' does not contain a '%s', looking up %s clusters is not supported",
+			source.name,
+			meta.KubeConfigKeyCACert,
+			provider,
+		)
+	}
+
+	exec, err := providerExecConfig(provider, data[meta.KubeConfigKeyCluster])
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := validateExec(exec, conf.AllowedExecCommands); err != nil {
+		return "", nil, err
+	}
+
+	restConfig.ExecProvider = exec
+	return clusterName, restConfig, nil
+}
+
+// requestServiceAccountToken requests a token of the ServiceAccount configured by a generic workload identity
+// kubeConfig. The ServiceAccount of the controller used by flux if none is configured is not known.
+func requestServiceAccountToken(
+	ctx context.Context,
+	gitopsClient dynamic.Interface,
+	namespace string,
+	data map[string]string,
+) (string, error) {
+	serviceAccountName := data[meta.KubeConfigKeyServiceAccountName]
+	if serviceAccountName == "" {
+		return "", fmt.Errorf(
+			"'%s' is required for the %s provider",
+			meta.KubeConfigKeyServiceAccountName,
+			kubeConfigProviderGeneric,
+		)
+	}
+
+	audiences := []string{data[meta.KubeConfigKeyAddress]}
+	if v := strings.TrimSpace(data[meta.KubeConfigKeyAudiences]); v != "" {
+		audiences = strings.Split(v, "\n")
+	}
+
+	request, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&authenticationv1.TokenRequest{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "authentication.k8s.io/v1", Kind: "TokenRequest"},
+		ObjectMeta: metav1.ObjectMeta{Name: serviceAccountName},
+		Spec:       authenticationv1.TokenRequestSpec{Audiences: audiences},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	element, err := gitopsClient.Resource(serviceAccountsGVR).Namespace(namespace).Create(
+		ctx,
+		&unstructured.Unstructured{Object: request},
+		metav1.CreateOptions{},
+		"token",
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token of service account %s/%s: %w", namespace, serviceAccountName, err)
+	}
+
+	token, _, _ := unstructured.NestedString(element.Object, "status", "token")
+	if token == "" {
+		return "", fmt.Errorf("no token issued for service account %s/%s", namespace, serviceAccountName)
+	}
+
+	return token, nil
+}
+
+// providerExecConfig returns the credential plugin of a cloud provider.
+func providerExecConfig(provider, cluster string) (*clientcmdapi.ExecConfig, error) {
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion:      execAPIVersion,
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+
+	switch provider {
+	case kubeConfigProviderAWS:
+		// arn:aws:eks:<region>:<account>:cluster/<name>
+		parts := strings.Split(cluster, ":")
+		name, ok := strings.CutPrefix(parts[len(parts)-1], "cluster/")
+		if len(parts) != 6 || !ok {
+			return nil, fmt.Errorf("invalid eks cluster arn %q", cluster)
+		}
+
+		exec.Command = "aws"
+		exec.Args = []string{"eks", "get-token", "--cluster-name", name, "--region", parts[3], "--output", "json"}
+	case kubeConfigProviderAzure:
+		exec.Command = "kubelogin"
+		exec.Args = []string{"get-token", "--login", "azurecli", "--server-id", azureKubernetesServerID}
+	case kubeConfigProviderGCP:
+		exec.Command = "gke-gcloud-auth-plugin"
+	default:
+		return nil, fmt.Errorf("unsupported kubeConfig provider %q", provider)
+	}
+
+	return exec, nil
+}
+
+// validateKubeConfig validates a kubeconfig read from the flux cluster. Its contents are controlled by whoever may
+// write the secret, so it must not reference local files nor run credential plugins which are not allowed.
+// Credential plugins are configured to never prompt while scanning.
+func validateKubeConfig(cfg *clientcmdapi.Config, allowed []string) error {
+	for name, cluster := range cfg.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("cluster %s: local file %q is not allowed, use certificate-authority-data", name, cluster.CertificateAuthority)
+		}
+	}
+
+	for name, authInfo := range cfg.AuthInfos {
+		for _, file := range []struct{ field, path string }{
+			{field: "client-certificate", path: authInfo.ClientCertificate},
+			{field: "client-key", path: authInfo.ClientKey},
+			{field: "tokenFile", path: authInfo.TokenFile},
+		} {
+			if file.path != "" {
+				return fmt.Errorf("user %s: local file %q (%s) is not allowed", name, file.path, file.field)
+			}
+		}
+
+		if authInfo.AuthProvider != nil {
+			return fmt.Errorf("user %s: auth provider %q is not allowed", name, authInfo.AuthProvider.Name)
+		}
+
+		if authInfo.Exec == nil {
+			continue
+		}
+
+		if err := validateExec(authInfo.Exec, allowed); err != nil {
+			return fmt.Errorf("user %s: %w", name, err)
+		}
+
+		authInfo.Exec.InteractiveMode = clientcmdapi.NeverExecInteractiveMode
+	}
+
+	return nil
+}
+
+// validateExec validates a credential plugin. Plugins run with the local credentials of the user and send the token
+// to the server of the kubeconfig, so only plugins allowed by the config are executed.
+// Builtin plugins may only be called with their known arguments and environment variables, other allowed plugins
+// are trusted including their arguments.
+func validateExec(exec *clientcmdapi.ExecConfig, allowed []string) error {
+	if !slices.Contains(allowed, exec.Command) {
+		return fmt.Errorf("credential plugin %q is not allowed, see allowedExecCommands", exec.Command)
+	}
+
+	plugin, ok := builtinExecCommands[exec.Command]
+	if !ok {
+		return nil
+	}
+
+	n := len(plugin.subcommand)
+	if len(exec.Args) < n || !slices.Equal(exec.Args[:n], plugin.subcommand) {
+		return fmt.Errorf("credential plugin %q must be called as %q", exec.Command, strings.Join(append([]string{exec.Command}, plugin.subcommand...), " "))
+	}
+
+	args := exec.Args[n:]
+	for i := 0; i < len(args); i++ {
+		flag, _, hasValue := strings.Cut(args[i], "=")
+		takesValue, ok := plugin.flags[flag]
+		if !ok || (hasValue && !takesValue) {
+			return fmt.Errorf("argument %q of credential plugin %q is not allowed", args[i], exec.Command)
+		}
+
+		if takesValue && !hasValue {
+			// the value is the next argument
+			i++
+		}
+	}
+
+	for _, env := range exec.Env {
+		if !slices.Contains(plugin.env, env.Name) {
+			return fmt.Errorf("environment variable %q of credential plugin %q is not allowed", env.Name, exec.Command)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,429 @@
+package detector
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestValidateExec(t *testing.T) {
+	tests := []struct {
+		name        string
+		exec        clientcmdapi.ExecConfig
+		allowed     []string
+		expectedErr string
+	}{
+		{
+			name: "aws",
+			exec: clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "prod", "--region=eu-west-1", "--output", "json"},
+				Env:     []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "prod"}},
+			},
+		},
+		{
+			name:        "aws without subcommand",
+			exec:        clientcmdapi.ExecConfig{Command: "aws", Args: []string{"s3", "ls"}},
+			expectedErr: `credential plugin "aws" must be called as "aws eks get-token"`,
+		},
+		{
+			name:        "aws with too few arguments",
+			exec:        clientcmdapi.ExecConfig{Command: "aws", Args: []string{"eks"}},
+			expectedErr: `credential plugin "aws" must be called as "aws eks get-token"`,
+		},
+		{
+			name: "aws with unknown flag",
+			exec: clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "prod", "--endpoint-url", "https://example.com"},
+			},
+			expectedErr: `argument "--endpoint-url" of credential plugin "aws" is not allowed`,
+		},
+		{
+			name: "flag values are not parsed as flags",
+			exec: clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "--endpoint-url"},
+			},
+		},
+		{
+			name: "aws with unknown environment variable",
+			exec: clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token"},
+				Env:     []clientcmdapi.ExecEnvVar{{Name: "AWS_CONFIG_FILE", Value: "/tmp/config"}},
+			},
+			expectedErr: `environment variable "AWS_CONFIG_FILE" of credential plugin "aws" is not allowed`,
+		},
+		{
+			name: "aws-iam-authenticator",
+			exec: clientcmdapi.ExecConfig{
+				Command: "aws-iam-authenticator",
+				Args:    []string{"token", "-i", "prod", "-r", "arn:aws:iam::123456789012:role/admin"},
+			},
+		},
+		{
+			name: "gke-gcloud-auth-plugin",
+			exec: clientcmdapi.ExecConfig{Command: "gke-gcloud-auth-plugin", Args: []string{"--use_application_default_credentials"}},
+		},
+		{
+			name: "boolean flag with value",
+			exec: clientcmdapi.ExecConfig{
+				Command: "gke-gcloud-auth-plugin",
+				Args:    []string{"--use_application_default_credentials=true"},
+			},
+			expectedErr: `argument "--use_application_default_credentials=true" of credential plugin "gke-gcloud-auth-plugin" is not allowed`,
+		},
+		{
+			name: "kubelogin",
+			exec: clientcmdapi.ExecConfig{
+				Command: "kubelogin",
+				Args:    []string{"get-token", "--login", "workloadidentity", "--server-id", azureKubernetesServerID},
+			},
+		},
+		{
+			name:        "unknown command",
+			exec:        clientcmdapi.ExecConfig{Command: "sh", Args: []string{"-c", "id"}},
+			expectedErr: `credential plugin "sh" is not allowed, see allowedExecCommands`,
+		},
+		{
+			name:        "builtin command referenced by path",
+			exec:        clientcmdapi.ExecConfig{Command: "/usr/local/bin/aws", Args: []string{"eks", "get-token"}},
+			expectedErr: `credential plugin "/usr/local/bin/aws" is not allowed, see allowedExecCommands`,
+		},
+		{
+			name:    "allowed command with any arguments",
+			exec:    clientcmdapi.ExecConfig{Command: "/usr/local/bin/my-plugin", Args: []string{"--any"}, Env: []clientcmdapi.ExecEnvVar{{Name: "ANY"}}},
+			allowed: []string{"/usr/local/bin/my-plugin"},
+		},
+		{
+			name:        "builtin command is not allowed by default",
+			exec:        clientcmdapi.ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}},
+			allowed:     []string{},
+			expectedErr: `credential plugin "aws" is not allowed, see allowedExecCommands`,
+		},
+		{
+			name:        "allowed builtin command is restricted",
+			exec:        clientcmdapi.ExecConfig{Command: "aws", Args: []string{"sts", "get-caller-identity"}},
+			allowed:     []string{"aws"},
+			expectedErr: `credential plugin "aws" must be called as "aws eks get-token"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allowed := test.allowed
+			if allowed == nil {
+				allowed = slices.Collect(maps.Keys(builtinExecCommands))
+			}
+
+			err := validateExec(&test.exec, allowed)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestProviderExecConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		cluster      string
+		expectedCmd  string
+		expectedArgs []string
+		expectedErr  string
+	}{
+		{
+			name:         "aws",
+			provider:     kubeConfigProviderAWS,
+			cluster:      "arn:aws:eks:eu-west-1:123456789012:cluster/prod",
+			expectedCmd:  "aws",
+			expectedArgs: []string{"eks", "get-token", "--cluster-name", "prod", "--region", "eu-west-1", "--output", "json"},
+		},
+		{
+			name:        "aws with invalid arn",
+			provider:    kubeConfigProviderAWS,
+			cluster:     "arn:aws:eks:eu-west-1:cluster/prod",
+			expectedErr: `invalid eks cluster arn "arn:aws:eks:eu-west-1:cluster/prod"`,
+		},
+		{
+			name:        "aws with a resource other than a cluster",
+			provider:    kubeConfigProviderAWS,
+			cluster:     "arn:aws:eks:eu-west-1:123456789012:nodegroup/prod",
+			expectedErr: `invalid eks cluster arn "arn:aws:eks:eu-west-1:123456789012:nodegroup/prod"`,
+		},
+		{
+			name:        "aws without cluster",
+			provider:    kubeConfigProviderAWS,
+			expectedErr: `invalid eks cluster arn ""`,
+		},
+		{
+			name:         "azure",
+			provider:     kubeConfigProviderAzure,
+			expectedCmd:  "kubelogin",
+			expectedArgs: []string{"get-token", "--login", "azurecli", "--server-id", azureKubernetesServerID},
+		},
+		{
+			name:        "gcp",
+			provider:    kubeConfigProviderGCP,
+			expectedCmd: "gke-gcloud-auth-plugin",
+		},
+		{
+			name:        "unknown provider",
+			provider:    "openstack",
+			expectedErr: `unsupported kubeConfig provider "openstack"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exec, err := providerExecConfig(test.provider, test.cluster)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, exec.Command, test.expectedCmd)
+			assert.DeepEqual(t, exec.Args, test.expectedArgs)
+			assert.Equal(t, exec.APIVersion, execAPIVersion)
+			assert.Equal(t, exec.InteractiveMode, clientcmdapi.NeverExecInteractiveMode)
+
+			// the plugins of the providers pass the validation of the builtin plugins once allowed
+			assert.NilError(t, validateExec(exec, []string{test.expectedCmd}))
+			assert.ErrorContains(t, validateExec(exec, nil), "is not allowed, see allowedExecCommands")
+		})
+	}
+}
+
+func TestNewKubeConfigSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		ref         meta.KubeConfigReference
+		expected    kubeConfigSource
+		expectedErr string
+	}{
+		{
+			name:     "secret",
+			ref:      meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "prod-kubeconfig"}},
+			expected: kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig"},
+		},
+		{
+			name:     "secret with key",
+			ref:      meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "prod-kubeconfig", Key: "config"}},
+			expected: kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig", key: "config"},
+		},
+		{
+			name:     "secret with the default key",
+			ref:      meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "prod-kubeconfig", Key: "value"}},
+			expected: kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig"},
+		},
+		{
+			name:     "config map",
+			ref:      meta.KubeConfigReference{ConfigMapRef: &meta.LocalObjectReference{Name: "prod"}},
+			expected: kubeConfigSource{kind: "ConfigMap", namespace: "apps", name: "prod"},
+		},
+		{
+			name:        "neither secret nor config map",
+			expectedErr: "kubeConfig references neither a secret nor a config map",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := newKubeConfigSource("apps", &test.ref)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, source, test.expected)
+		})
+	}
+}
+
+func TestKubeConfigSourceClusterName(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      kubeConfigSource
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name:     "secret",
+			source:   kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig"},
+			expected: "apps/prod-kubeconfig",
+		},
+		{
+			name:     "secret with key",
+			source:   kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig", key: "config"},
+			expected: "apps/prod-kubeconfig/config",
+		},
+		{
+			name:     "config map",
+			source:   kubeConfigSource{kind: "ConfigMap", namespace: "apps", name: "prod"},
+			expected: "apps/prod",
+		},
+		{
+			name:        "annotated",
+			source:      kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig", key: "config"},
+			annotations: map[string]string{clusterNameAnnotation: "prod"},
+			expected:    "prod",
+		},
+		{
+			name:        "empty annotation",
+			source:      kubeConfigSource{kind: "Secret", namespace: "apps", name: "prod-kubeconfig"},
+			annotations: map[string]string{clusterNameAnnotation: ""},
+			expected:    "apps/prod-kubeconfig",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.source.clusterName(test.annotations), test.expected)
+		})
+	}
+}
+
+func TestRestConfigFromConfigMapGeneric(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          v2.Config
+		expectedToken string
+		expectedErr   string
+	}{
+		{
+			name:        "token requests are not enabled",
+			expectedErr: "the generic provider requests a token of service account apps/deployer, see requestServiceAccountTokens",
+		},
+		{
+			name:          "token requests are enabled",
+			conf:          v2.Config{RequestServiceAccountTokens: ptr.To(true)},
+			expectedToken: "issued",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "prod", "namespace": "apps"},
+				"data": map[string]interface{}{
+					meta.KubeConfigKeyProvider:           kubeConfigProviderGeneric,
+					meta.KubeConfigKeyAddress:            "https://prod.example.com",
+					meta.KubeConfigKeyServiceAccountName: "deployer",
+				},
+			}}
+
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), configMap)
+			client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &unstructured.Unstructured{Object: map[string]interface{}{
+					"status": map[string]interface{}{"token": "issued"},
+				}}, nil
+			})
+
+			source := kubeConfigSource{kind: "ConfigMap", namespace: "apps", name: "prod"}
+			_, restConfig, err := restConfigFromConfigMap(context.TODO(), client, source, &test.conf)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				for _, action := range client.Actions() {
+					assert.Assert(t, action.GetVerb() != "create", "no token must be requested")
+				}
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, restConfig.BearerToken, test.expectedToken)
+		})
+	}
+}
+
+func TestValidateKubeConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         clientcmdapi.Config
+		expectedErr string
+	}{
+		{
+			name: "inline credentials",
+			cfg: clientcmdapi.Config{
+				Clusters:  map[string]*clientcmdapi.Cluster{"prod": {Server: "https://prod.example.com", CertificateAuthorityData: []byte("ca")}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {Token: "secret", ClientCertificateData: []byte("cert")}},
+			},
+		},
+		{
+			name: "certificate authority file",
+			cfg: clientcmdapi.Config{
+				Clusters: map[string]*clientcmdapi.Cluster{"prod": {Server: "https://prod.example.com", CertificateAuthority: "/etc/ca.crt"}},
+			},
+			expectedErr: `cluster prod: local file "/etc/ca.crt" is not allowed, use certificate-authority-data`,
+		},
+		{
+			name:        "token file",
+			cfg:         clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {TokenFile: "/var/run/token"}}},
+			expectedErr: `user admin: local file "/var/run/token" (tokenFile) is not allowed`,
+		},
+		{
+			name:        "client key file",
+			cfg:         clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {ClientKey: "/home/user/.ssh/id_rsa"}}},
+			expectedErr: `user admin: local file "/home/user/.ssh/id_rsa" (client-key) is not allowed`,
+		},
+		{
+			name:        "client certificate file",
+			cfg:         clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {ClientCertificate: "/tmp/cert"}}},
+			expectedErr: `user admin: local file "/tmp/cert" (client-certificate) is not allowed`,
+		},
+		{
+			name: "auth provider",
+			cfg: clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{
+				"admin": {AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc"}},
+			}},
+			expectedErr: `user admin: auth provider "oidc" is not allowed`,
+		},
+		{
+			name: "exec plugin is not allowed",
+			cfg: clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{
+				"admin": {Exec: &clientcmdapi.ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}}},
+			}},
+			expectedErr: `user admin: credential plugin "aws" is not allowed, see allowedExecCommands`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateKubeConfig(&test.cfg, nil)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestValidateKubeConfigNeverPrompts(t *testing.T) {
+	exec := &clientcmdapi.ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}}
+	cfg := clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {Exec: exec}}}
+
+	assert.NilError(t, validateKubeConfig(&cfg, []string{"aws"}))
+	assert.Equal(t, exec.InteractiveMode, clientcmdapi.NeverExecInteractiveMode)
+}
@@ -0,0 +1,112 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+var namespacesGVR = schema.GroupVersionResource{
+	Group:    "",
+	Version:  "v1",
+	Resource: "namespaces",
+}
+
+// maxNamespaceFieldSelectors limits the namespaces excluded by a field selector. The api server evaluates each
+// term for every object listed, beyond the limit the excluded namespaces are filtered after listing them.
+const maxNamespaceFieldSelectors = 10
+
+// namespaceScope describes how the namespaced resources of a cluster are listed.
+type namespaceScope struct {
+	// restricted lists namespaced resources per namespace, otherwise they are listed from all namespaces at once.
+	restricted bool
+	// namespaces are the namespaces listed one by one if the scope is restricted.
+	namespaces []string
+	// fieldSelector excludes namespaces if resources are listed from all namespaces at once.
+	// It is empty if more namespaces than maxNamespaceFieldSelectors are excluded.
+	fieldSelector string
+	// clusterScoped lists cluster scoped resources as well.
+	clusterScoped bool
+}
+
+// resolveNamespaceScope resolves the included and excluded namespaces of the config on a cluster.
+// The cheaper of listing the selected namespaces one by one and listing all namespaces at once
+// excluding the others by a field selector is chosen. Namespaces are filtered by collector.IgnoreNamespaces
+// after listing in any case.
+// The namespace requested on the command line is the only namespace listed.
+func resolveNamespaceScope(
+	ctx context.Context,
+	client dynamic.Interface,
+	conf *v2.Config,
+	namespace string,
+) (namespaceScope, error) {
+	if namespace != "" {
+		return namespaceScope{
+			restricted:    true,
+			namespaces:    []string{namespace},
+			clusterScoped: ptr.Deref(conf.IncludeClusterScoped, false),
+		}, nil
+	}
+
+	scope := namespaceScope{clusterScoped: len(conf.IncludeNamespaces) == 0 || ptr.Deref(conf.IncludeClusterScoped, false)}
+	if len(conf.IncludeNamespaces) == 0 && len(conf.ExcludeNamespaces) == 0 {
+		return scope, nil
+	}
+
+	include, err := v2.CompileNamespacePatterns(conf.IncludeNamespaces)
+	if err != nil {
+		return scope, err
+	}
+
+	exclude, err := v2.CompileNamespacePatterns(conf.ExcludeNamespaces)
+	if err != nil {
+		return scope, err
+	}
+
+	list, err := client.Resource(namespacesGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// namespaced resources are filtered after listing them instead
+		return scope, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var selected, rejected []string
+	for _, ns := range list.Items {
+		name := ns.GetName()
+		matches := func(re *regexp.Regexp) bool {
+			return re.MatchString(name)
+		}
+
+		if (len(include) == 0 || slices.ContainsFunc(include, matches)) && !slices.ContainsFunc(exclude, matches) {
+			selected = append(selected, name)
+		} else {
+			rejected = append(rejected, name)
+		}
+	}
+
+	if len(selected) < len(rejected) {
+		scope.restricted = true
+		scope.namespaces = selected
+		return scope, nil
+	}
+
+	if len(rejected) > maxNamespaceFieldSelectors {
+		return scope, nil
+	}
+
+	selectors := make([]string, 0, len(rejected))
+	for _, name := range rejected {
+		selectors = append(selectors, "metadata.namespace!="+name)
+	}
+
+	scope.fieldSelector = strings.Join(selectors, ",")
+	return scope, nil
+}
@@ -0,0 +1,100 @@
+package detector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func newNamespaceClient(names ...string) *dynamicfake.FakeDynamicClient {
+	objects := make([]runtime.Object, 0, len(names))
+	for _, name := range names {
+		ns := &unstructured.Unstructured{}
+		ns.SetAPIVersion("v1")
+		ns.SetKind("Namespace")
+		ns.SetName(name)
+		objects = append(objects, ns)
+	}
+
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{namespacesGVR: "NamespaceList"}, objects...)
+}
+
+func TestResolveNamespaceScope(t *testing.T) {
+	var many []string
+	for i := range maxNamespaceFieldSelectors + 1 {
+		many = append(many, fmt.Sprintf("apps-%d", i), fmt.Sprintf("kube-%d", i))
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		conf       v2.Config
+		namespace  string
+		expected   namespaceScope
+	}{
+		{
+			name:       "no namespace restrictions",
+			namespaces: []string{"apps", "kube-system"},
+			expected:   namespaceScope{clusterScoped: true},
+		},
+		{
+			name:       "namespace flag",
+			namespaces: []string{"apps", "kube-system"},
+			conf:       v2.Config{ExcludeNamespaces: []string{"apps"}},
+			namespace:  "apps",
+			expected:   namespaceScope{restricted: true, namespaces: []string{"apps"}},
+		},
+		{
+			name:       "namespace flag including cluster scoped resources",
+			namespaces: []string{"apps"},
+			conf:       v2.Config{IncludeClusterScoped: ptr.To(true)},
+			namespace:  "apps",
+			expected:   namespaceScope{restricted: true, namespaces: []string{"apps"}, clusterScoped: true},
+		},
+		{
+			name:       "few included namespaces are listed one by one",
+			namespaces: []string{"apps", "kube-public", "kube-system", "team-a", "team-b"},
+			conf:       v2.Config{IncludeNamespaces: []string{"team-*"}, ExcludeNamespaces: []string{"team-b"}},
+			expected:   namespaceScope{restricted: true, namespaces: []string{"team-a"}},
+		},
+		{
+			name:       "few excluded namespaces are excluded by a field selector",
+			namespaces: []string{"apps", "kube-public", "kube-system", "team-a", "team-b"},
+			conf:       v2.Config{ExcludeNamespaces: []string{"kube-(public|system)"}},
+			expected: namespaceScope{
+				fieldSelector: "metadata.namespace!=kube-public,metadata.namespace!=kube-system",
+				clusterScoped: true,
+			},
+		},
+		{
+			name:       "many excluded namespaces are filtered after listing",
+			namespaces: many,
+			conf:       v2.Config{ExcludeNamespaces: []string{"kube-*"}},
+			expected:   namespaceScope{clusterScoped: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scope, err := resolveNamespaceScope(t.Context(), newNamespaceClient(test.namespaces...), &test.conf, test.namespace)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, scope, test.expected, cmp.AllowUnexported(namespaceScope{}))
+		})
+	}
+}
+
+func TestResolveNamespaceScopeInvalidPattern(t *testing.T) {
+	conf := &v2.Config{IncludeNamespaces: []string{"team-(a"}}
+	_, err := resolveNamespaceScope(t.Context(), newNamespaceClient("team-a"), conf, "")
+	assert.ErrorContains(t, err, "invalid pattern")
+}
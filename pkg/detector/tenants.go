@@ -0,0 +1,170 @@
+package detector
+
+import (
+	"fmt"
+	"slices"
+
+	helmapi "github.com/fluxcd/helm-controller/api/v2"
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+// tenant is a ServiceAccount flux impersonates while reconciling Kustomizations and HelmReleases on a cluster.
+type tenant struct {
+	namespace      string
+	serviceAccount string
+	// namespaces are the namespaces the tenant reconciles into
+	namespaces []string
+	// owners are the Kustomizations and HelmReleases reconciled as the tenant by kind
+	owners map[string]map[types.NamespacedName]struct{}
+}
+
+func (t *tenant) String() string {
+	return t.namespace + "/" + t.serviceAccount
+}
+
+func (t *tenant) addOwner(kind string, owner types.NamespacedName, targetNamespace string) {
+	if t.owners[kind] == nil {
+		t.owners[kind] = make(map[types.NamespacedName]struct{})
+	}
+
+	t.owners[kind][owner] = struct{}{}
+	if !slices.Contains(t.namespaces, targetNamespace) {
+		t.namespaces = append(t.namespaces, targetNamespace)
+	}
+}
+
+// owns returns true if the resource is labeled with a Kustomization or HelmRelease reconciled as the tenant.
+func (t *tenant) owns(res unstructured.Unstructured) bool {
+	kind, owner, ok := collector.FluxOwner(res)
+	if !ok {
+		return false
+	}
+
+	_, ok = t.owners[kind][owner]
+	return ok
+}
+
+// impersonate returns clients of the cluster impersonating the tenant.
+func (t *tenant) impersonate(clients clusterClients) (clusterClients, error) {
+	cfg := rest.CopyConfig(clients.config)
+	cfg.Impersonate = rest.ImpersonationConfig{
+		UserName: fmt.Sprintf("system:serviceaccount:%s:%s", t.namespace, t.serviceAccount),
+	}
+
+	return newClusterClients(cfg)
+}
+
+// TenantSummary summarizes the scan of a cluster impersonating a tenant.
+type TenantSummary struct {
+	// Cluster is the key of the scan within the detected zombies.
+	Cluster string
+	// Resources is the number of resources visible to the tenant.
+	Resources int
+	// Owned is the number of resources reconciled by the Kustomizations and HelmReleases of the tenant.
+	Owned int
+	// Zombies is the number of zombies visible to the tenant.
+	Zombies int
+}
+
+// contextSeparator separates the kubeconfig context from the cluster within the key of a scan.
+const contextSeparator = " | "
+
+// clusterScan is a scan of a cluster, optionally impersonating a tenant.
+type clusterScan struct {
+	// context is the kubeconfig context the cluster was discovered from if multiple contexts are scanned
+	context string
+	cluster string
+	clients clusterClients
+	tenant  *tenant
+}
+
+// key returns the key of the scan within the detected zombies.
+// Both context and cluster names may contain slashes, they are separated by a pipe instead.
+func (s clusterScan) key() string {
+	key := s.cluster
+	if s.context != "" {
+		key = s.context + contextSeparator + key
+	}
+
+	if s.tenant != nil {
+		key += " as " + s.tenant.String()
+	}
+
+	return key
+}
+
+// tenantScans returns a scan per cluster and tenant of the Kustomizations and HelmReleases with a
+// spec.serviceAccountName. Flux impersonates the ServiceAccount of the namespace of the Kustomization or HelmRelease
+// on the cluster the resources are reconciled to. Resources reconciled by the controller itself are not scanned.
+// If namespace is set only the tenants of the namespace are scanned.
+func tenantScans(resources *gitopsResources, namespace string) ([]clusterScan, error) {
+	tenants := make(map[string]map[types.NamespacedName]*tenant)
+	add := func(cluster, kind string, owner types.NamespacedName, serviceAccount, targetNamespace string) {
+		if serviceAccount == "" || (namespace != "" && owner.Namespace != namespace) {
+			return
+		}
+
+		if tenants[cluster] == nil {
+			tenants[cluster] = make(map[types.NamespacedName]*tenant)
+		}
+
+		key := types.NamespacedName{Namespace: owner.Namespace, Name: serviceAccount}
+		t, ok := tenants[cluster][key]
+		if !ok {
+			t = &tenant{
+				namespace:      owner.Namespace,
+				serviceAccount: serviceAccount,
+				namespaces:     []string{owner.Namespace},
+				owners:         make(map[string]map[types.NamespacedName]struct{}),
+			}
+			tenants[cluster][key] = t
+		}
+
+		t.addOwner(kind, owner, targetNamespace)
+	}
+
+	for _, ks := range resources.kustomizations {
+		cluster, ok := resources.targetCluster(ks.Namespace, ks.Spec.KubeConfig)
+		if !ok {
+			continue
+		}
+
+		targetNamespace := ks.Spec.TargetNamespace
+		if targetNamespace == "" {
+			targetNamespace = ks.Namespace
+		}
+
+		owner := types.NamespacedName{Namespace: ks.Namespace, Name: ks.Name}
+		add(cluster, ksapi.KustomizationKind, owner, ks.Spec.ServiceAccountName, targetNamespace)
+	}
+
+	for _, hr := range resources.helmReleases {
+		cluster, ok := resources.targetCluster(hr.Namespace, hr.Spec.KubeConfig)
+		if !ok {
+			continue
+		}
+
+		owner := types.NamespacedName{Namespace: hr.Namespace, Name: hr.Name}
+		add(cluster, helmapi.HelmReleaseKind, owner, hr.Spec.ServiceAccountName, hr.GetReleaseNamespace())
+	}
+
+	var scans []clusterScan
+	for cluster, clusterTenants := range tenants {
+		for _, t := range clusterTenants {
+			clients, err := t.impersonate(resources.clusters[cluster])
+			if err != nil {
+				return nil, fmt.Errorf("[%s] failed to impersonate tenant %s: %w", cluster, t, err)
+			}
+
+			slices.Sort(t.namespaces)
+			scans = append(scans, clusterScan{cluster: cluster, clients: clients, tenant: t})
+		}
+	}
+
+	return scans, nil
+}
@@ -0,0 +1,231 @@
+package detector
+
+import (
+	"testing"
+
+	helmapi "github.com/fluxcd/helm-controller/api/v2"
+	ksapi "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func TestTenantScans(t *testing.T) {
+	remoteRef := &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "remote-kubeconfig"}}
+	remoteSource := kubeConfigSource{kind: "Secret", namespace: "apps", name: "remote-kubeconfig"}
+
+	kustomization := func(namespace, name, serviceAccount, targetNamespace string, ref *meta.KubeConfigReference) ksapi.Kustomization {
+		return ksapi.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: ksapi.KustomizationSpec{
+				ServiceAccountName: serviceAccount,
+				TargetNamespace:    targetNamespace,
+				KubeConfig:         ref,
+			},
+		}
+	}
+
+	helmRelease := func(namespace, name, serviceAccount, targetNamespace string, ref *meta.KubeConfigReference) helmapi.HelmRelease {
+		return helmapi.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: helmapi.HelmReleaseSpec{
+				ServiceAccountName: serviceAccount,
+				TargetNamespace:    targetNamespace,
+				KubeConfig:         ref,
+			},
+		}
+	}
+
+	owners := func(kind string, names ...types.NamespacedName) map[string]map[types.NamespacedName]struct{} {
+		owners := map[string]map[types.NamespacedName]struct{}{kind: {}}
+		for _, name := range names {
+			owners[kind][name] = struct{}{}
+		}
+
+		return owners
+	}
+
+	tests := []struct {
+		name           string
+		namespace      string
+		kustomizations []ksapi.Kustomization
+		helmReleases   []helmapi.HelmRelease
+		expected       map[string]*tenant
+	}{
+		{
+			name: "resources reconciled by the controller are not scanned",
+			kustomizations: []ksapi.Kustomization{
+				kustomization("apps", "podinfo", "", "", nil),
+			},
+			helmReleases: []helmapi.HelmRelease{
+				helmRelease("apps", "redis", "", "", nil),
+			},
+			expected: map[string]*tenant{},
+		},
+		{
+			name: "kustomization target namespace",
+			kustomizations: []ksapi.Kustomization{
+				kustomization("apps", "podinfo", "deployer", "podinfo", nil),
+				kustomization("apps", "frontend", "deployer", "", nil),
+			},
+			expected: map[string]*tenant{
+				"self as apps/deployer": {
+					namespace:      "apps",
+					serviceAccount: "deployer",
+					namespaces:     []string{"apps", "podinfo"},
+					owners: owners(ksapi.KustomizationKind,
+						types.NamespacedName{Namespace: "apps", Name: "podinfo"},
+						types.NamespacedName{Namespace: "apps", Name: "frontend"},
+					),
+				},
+			},
+		},
+		{
+			name: "helm release namespace falls back to the namespace of the release",
+			helmReleases: []helmapi.HelmRelease{
+				helmRelease("apps", "redis", "deployer", "", nil),
+				helmRelease("apps", "postgres", "deployer", "databases", nil),
+			},
+			expected: map[string]*tenant{
+				"self as apps/deployer": {
+					namespace:      "apps",
+					serviceAccount: "deployer",
+					namespaces:     []string{"apps", "databases"},
+					owners: owners(helmapi.HelmReleaseKind,
+						types.NamespacedName{Namespace: "apps", Name: "redis"},
+						types.NamespacedName{Namespace: "apps", Name: "postgres"},
+					),
+				},
+			},
+		},
+		{
+			name: "service accounts are tenants per namespace and cluster",
+			kustomizations: []ksapi.Kustomization{
+				kustomization("apps", "podinfo", "deployer", "", nil),
+				kustomization("apps", "remote", "deployer", "", remoteRef),
+				kustomization("team", "podinfo", "deployer", "", nil),
+				kustomization("apps", "unreachable", "deployer", "", &meta.KubeConfigReference{
+					SecretRef: &meta.SecretKeyReference{Name: "unreachable-kubeconfig"},
+				}),
+			},
+			expected: map[string]*tenant{
+				"self as apps/deployer": {
+					namespace:      "apps",
+					serviceAccount: "deployer",
+					namespaces:     []string{"apps"},
+					owners:         owners(ksapi.KustomizationKind, types.NamespacedName{Namespace: "apps", Name: "podinfo"}),
+				},
+				"apps/remote-kubeconfig as apps/deployer": {
+					namespace:      "apps",
+					serviceAccount: "deployer",
+					namespaces:     []string{"apps"},
+					owners:         owners(ksapi.KustomizationKind, types.NamespacedName{Namespace: "apps", Name: "remote"}),
+				},
+				"self as team/deployer": {
+					namespace:      "team",
+					serviceAccount: "deployer",
+					namespaces:     []string{"team"},
+					owners:         owners(ksapi.KustomizationKind, types.NamespacedName{Namespace: "team", Name: "podinfo"}),
+				},
+			},
+		},
+		{
+			name: "kustomizations and helm releases of a service account are deduplicated",
+			kustomizations: []ksapi.Kustomization{
+				kustomization("apps", "podinfo", "deployer", "", nil),
+			},
+			helmReleases: []helmapi.HelmRelease{
+				helmRelease("apps", "redis", "deployer", "apps", nil),
+			},
+			expected: map[string]*tenant{
+				"self as apps/deployer": {
+					namespace:      "apps",
+					serviceAccount: "deployer",
+					namespaces:     []string{"apps"},
+					owners: map[string]map[types.NamespacedName]struct{}{
+						ksapi.KustomizationKind: {{Namespace: "apps", Name: "podinfo"}: {}},
+						helmapi.HelmReleaseKind: {{Namespace: "apps", Name: "redis"}: {}},
+					},
+				},
+			},
+		},
+		{
+			name:      "restricted to the namespace",
+			namespace: "team",
+			kustomizations: []ksapi.Kustomization{
+				kustomization("apps", "podinfo", "deployer", "", nil),
+				kustomization("team", "podinfo", "deployer", "", nil),
+			},
+			expected: map[string]*tenant{
+				"self as team/deployer": {
+					namespace:      "team",
+					serviceAccount: "deployer",
+					namespaces:     []string{"team"},
+					owners:         owners(ksapi.KustomizationKind, types.NamespacedName{Namespace: "team", Name: "podinfo"}),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resources := &gitopsResources{
+				kustomizations: test.kustomizations,
+				helmReleases:   test.helmReleases,
+				clusters: map[string]clusterClients{
+					fluxClusterName:         {config: &rest.Config{Host: "https://self.example.com"}},
+					remoteSource.identity(): {config: &rest.Config{Host: "https://remote.example.com"}},
+				},
+				sources: map[kubeConfigSource]string{remoteSource: remoteSource.identity()},
+			}
+
+			scans, err := tenantScans(resources, test.namespace)
+			assert.NilError(t, err)
+
+			tenants := make(map[string]*tenant)
+			for _, scan := range scans {
+				tenants[scan.key()] = scan.tenant
+
+				// the clients of the cluster impersonate the service account of the tenant
+				assert.Equal(t, scan.clients.config.Host, resources.clusters[scan.cluster].config.Host)
+				assert.Equal(t, scan.clients.config.Impersonate.UserName,
+					"system:serviceaccount:"+scan.tenant.namespace+":"+scan.tenant.serviceAccount)
+			}
+
+			assert.DeepEqual(t, tenants, test.expected, cmp.AllowUnexported(tenant{}))
+		})
+	}
+}
+
+func TestClusterScanKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		scan     clusterScan
+		expected string
+	}{
+		{
+			name:     "cluster",
+			scan:     clusterScan{cluster: "apps/prod-kubeconfig"},
+			expected: "apps/prod-kubeconfig",
+		},
+		{
+			name:     "context",
+			scan:     clusterScan{context: "arn:aws:eks:eu-west-1:123456789012:cluster/prod", cluster: "apps/prod-kubeconfig"},
+			expected: "arn:aws:eks:eu-west-1:123456789012:cluster/prod | apps/prod-kubeconfig",
+		},
+		{
+			name:     "tenant",
+			scan:     clusterScan{context: "prod", cluster: "self", tenant: &tenant{namespace: "apps", serviceAccount: "deployer"}},
+			expected: "prod | self as apps/deployer",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.scan.key(), test.expected)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package detector
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+const clusterScope = "(cluster scoped)"
+
+type treeNode struct {
+	res      unstructured.Unstructured
+	zombie   bool
+	children []*treeNode
+}
+
+// printTree renders zombies grouped by cluster, namespace and root owner similar to kubectl tree.
+// Resources referenced by a zombie (mounted ConfigMaps and Secrets, used ServiceAccounts, ...) are listed below it.
+func (d *Detector) printTree(w io.Writer, allZombies map[string][]unstructured.Unstructured) error {
+	for _, clusterName := range slices.Sorted(maps.Keys(allZombies)) {
+		if _, err := fmt.Fprintf(w, "[%s]\n", clusterName); err != nil {
+			return err
+		}
+
+		namespaces := buildTree(allZombies[clusterName], d.referenceIndex(clusterName))
+		for i, namespace := range slices.Sorted(maps.Keys(namespaces)) {
+			last := i == len(namespaces)-1
+			if _, err := fmt.Fprintf(w, "%s%s\n", treeBranch(last), namespace); err != nil {
+				return err
+			}
+
+			if err := printTreeNodes(w, treeIndent(last), namespaces[namespace], map[types.UID]struct{}{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func printTreeNodes(w io.Writer, indent string, nodes []*treeNode, printed map[types.UID]struct{}) error {
+	for i, node := range nodes {
+		last := i == len(nodes)-1
+		line := node.res.GroupVersionKind().GroupKind().String() + "/" + node.res.GetName()
+		if node.zombie {
+			line += describeZombie(node.res)
+		} else {
+			line += " [" + collector.NotReported + "]"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", indent, treeBranch(last), line); err != nil {
+			return err
+		}
+
+		// the same resource might be referenced multiple times, its subtree is only rendered once
+		if _, ok := printed[node.res.GetUID()]; ok {
+			continue
+		}
+		printed[node.res.GetUID()] = struct{}{}
+
+		if err := printTreeNodes(w, indent+treeIndent(last), node.children, printed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func treeBranch(last bool) string {
+	if last {
+		return "└── "
+	}
+
+	return "├── "
+}
+
+func treeIndent(last bool) string {
+	if last {
+		return "    "
+	}
+
+	return "│   "
+}
+
+// buildTree builds the root nodes per namespace.
+// Zombies are nested below their owner, referenced resources below the zombie referencing them.
+func buildTree(zombies []unstructured.Unstructured, references *collector.ReferenceIndex) map[string][]*treeNode {
+	nodes := make(map[types.UID]*treeNode, len(zombies))
+	for _, zombie := range zombies {
+		nodes[zombie.GetUID()] = &treeNode{res: zombie, zombie: true}
+	}
+
+	nested := make(map[types.UID]struct{})
+	for _, zombie := range zombies {
+		node := nodes[zombie.GetUID()]
+
+		if ref := collector.ControllerOf(zombie.GetOwnerReferences()); ref != nil {
+			if parent, ok := nodes[ref.UID]; ok {
+				parent.children = append(parent.children, node)
+				nested[zombie.GetUID()] = struct{}{}
+			}
+		}
+
+		if references == nil {
+			continue
+		}
+
+		for _, dependent := range references.Dependents(zombie) {
+			child, ok := nodes[dependent.GetUID()]
+			if ok {
+				nested[dependent.GetUID()] = struct{}{}
+			} else {
+				child = &treeNode{res: dependent}
+			}
+
+			node.children = append(node.children, child)
+		}
+	}
+
+	var roots []*treeNode
+	reached := make(map[types.UID]struct{})
+	for _, zombie := range zombies {
+		if _, ok := nested[zombie.GetUID()]; !ok {
+			roots = append(roots, nodes[zombie.GetUID()])
+			reachTreeNodes(nodes[zombie.GetUID()], reached)
+		}
+	}
+
+	// zombies owning or referencing each other in a cycle are all nested, the first one of each cycle becomes a root
+	cyclic := slices.Clone(zombies)
+	slices.SortStableFunc(cyclic, func(a, b unstructured.Unstructured) int {
+		return strings.Compare(collector.ObjectReference(a), collector.ObjectReference(b))
+	})
+
+	for _, zombie := range cyclic {
+		if _, ok := reached[zombie.GetUID()]; !ok {
+			roots = append(roots, nodes[zombie.GetUID()])
+			reachTreeNodes(nodes[zombie.GetUID()], reached)
+		}
+	}
+
+	namespaces := make(map[string][]*treeNode)
+	for _, root := range roots {
+		namespace := root.res.GetNamespace()
+		if namespace == "" {
+			namespace = clusterScope
+		}
+
+		namespaces[namespace] = append(namespaces[namespace], root)
+	}
+
+	for _, node := range nodes {
+		sortTreeNodes(node.children)
+	}
+
+	for _, roots := range namespaces {
+		sortTreeNodes(roots)
+	}
+
+	return namespaces
+}
+
+// reachTreeNodes marks a node and all nodes below it as reached.
+func reachTreeNodes(node *treeNode, reached map[types.UID]struct{}) {
+	if _, ok := reached[node.res.GetUID()]; ok {
+		return
+	}
+
+	reached[node.res.GetUID()] = struct{}{}
+	for _, child := range node.children {
+		reachTreeNodes(child, reached)
+	}
+}
+
+func sortTreeNodes(nodes []*treeNode) {
+	slices.SortStableFunc(nodes, func(a, b *treeNode) int {
+		return strings.Compare(collector.ObjectReference(a.res), collector.ObjectReference(b.res))
+	})
+}
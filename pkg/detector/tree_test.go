@@ -0,0 +1,100 @@
+package detector
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	"github.com/raffis/gitops-zombies/pkg/collector"
+)
+
+func newTreeZombie(name, uid string, owner *unstructured.Unstructured) unstructured.Unstructured {
+	res := unstructured.Unstructured{}
+	res.SetAPIVersion("example.com/v1")
+	res.SetKind("Widget")
+	res.SetNamespace("test")
+	res.SetName(name)
+	res.SetUID(types.UID(uid))
+	if owner != nil {
+		res.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: owner.GetAPIVersion(),
+			Kind:       owner.GetKind(),
+			Name:       owner.GetName(),
+			UID:        owner.GetUID(),
+			Controller: ptr.To(true),
+		}})
+	}
+
+	return res
+}
+
+func treeNames(nodes []*treeNode) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.res.GetName())
+	}
+
+	return names
+}
+
+func TestBuildTree(t *testing.T) {
+	root := newTreeZombie("root", "1", nil)
+	child := newTreeZombie("child", "2", &root)
+
+	namespaces := buildTree([]unstructured.Unstructured{child, root}, nil)
+	assert.DeepEqual(t, treeNames(namespaces["test"]), []string{"root"})
+	assert.DeepEqual(t, treeNames(namespaces["test"][0].children), []string{"child"})
+}
+
+func TestBuildTreeCycle(t *testing.T) {
+	a := newTreeZombie("a", "1", nil)
+	b := newTreeZombie("b", "2", &a)
+	c := newTreeZombie("c", "3", &b)
+	a = newTreeZombie("a", "1", &c)
+
+	namespaces := buildTree([]unstructured.Unstructured{c, b, a}, nil)
+	assert.DeepEqual(t, treeNames(namespaces["test"]), []string{"a"})
+	assert.DeepEqual(t, treeNames(namespaces["test"][0].children), []string{"b"})
+
+	var buf bytes.Buffer
+	err := printTreeNodes(&buf, "", namespaces["test"], map[types.UID]struct{}{})
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), `└── Widget.example.com/a
+    └── Widget.example.com/b
+        └── Widget.example.com/c
+            └── Widget.example.com/a
+`)
+}
+
+func TestPrintTreeReferences(t *testing.T) {
+	pod := unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{"serviceAccountName": "web"},
+	}}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("test")
+	pod.SetName("web")
+	pod.SetUID("1")
+
+	serviceAccount := unstructured.Unstructured{}
+	serviceAccount.SetAPIVersion("v1")
+	serviceAccount.SetKind("ServiceAccount")
+	serviceAccount.SetNamespace("test")
+	serviceAccount.SetName("web")
+	serviceAccount.SetUID("2")
+
+	references := collector.NewReferenceIndex([]unstructured.Unstructured{pod, serviceAccount})
+	namespaces := buildTree([]unstructured.Unstructured{pod}, references)
+
+	var buf bytes.Buffer
+	err := printTreeNodes(&buf, "", namespaces["test"], map[types.UID]struct{}{})
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), `└── Pod/web
+    └── ServiceAccount/web [not reported]
+`)
+}
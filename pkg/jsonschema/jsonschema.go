@@ -0,0 +1,249 @@
+// Package jsonschema generates JSON schemas from the gitops-zombies config api types.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a subset of a JSON schema (draft-07) sufficient to describe the config api types.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+var (
+	typeMetaType = reflect.TypeFor[metav1.TypeMeta]()
+	durationType = reflect.TypeFor[metav1.Duration]()
+)
+
+type generator struct {
+	docs        map[string]string
+	definitions map[string]*Schema
+}
+
+// Generate generates the JSON schema for the kind obj of the api group version gv.
+// Descriptions are taken from the doc comments of the go source files within dir which declare the types of obj.
+func Generate(dir string, gv schema.GroupVersion, obj any) ([]byte, error) {
+	docs, err := parseDocs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct but got %s", t)
+	}
+
+	g := &generator{docs: docs, definitions: make(map[string]*Schema)}
+	root := g.object(t)
+	root.Schema = draft
+	root.Title = fmt.Sprintf("gitops-zombies %s %s", t.Name(), gv)
+	root.Properties["apiVersion"] = &Schema{Type: "string", Const: gv.String()}
+	root.Properties["kind"] = &Schema{Type: "string", Const: t.Name()}
+	root.Required = append([]string{"apiVersion", "kind"}, root.Required...)
+	if len(g.definitions) > 0 {
+		root.Definitions = g.definitions
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// object returns the schema of a struct, inlined structs are flattened.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:                 "object",
+		Description:          g.docs[t.Name()],
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+
+	g.addFields(s, t)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			if field.Type == typeMetaType {
+				s.Properties["apiVersion"] = &Schema{Type: "string", Description: "APIVersion of the resource."}
+				s.Properties["kind"] = &Schema{Type: "string", Description: "Kind of the resource."}
+				continue
+			}
+
+			g.addFields(s, field.Type)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.schema(field.Type)
+		if description := g.docs[t.Name()+"."+field.Name]; description != "" {
+			if prop.Ref != "" {
+				// keep the shared definition untouched
+				prop = &Schema{Ref: prop.Ref}
+			}
+
+			prop.Description = description
+		}
+
+		s.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Pointer:
+		return g.schema(t.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// register before recursing to support self referencing types
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.object(t)
+		}
+
+		return &Schema{Ref: "#/definitions/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+// parseDocs returns the doc comments of all types (Type) and struct fields (Type.Field) declared within dir.
+func parseDocs(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			decl, ok := node.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				return true
+			}
+
+			for _, spec := range decl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(decl.Specs) == 1 {
+					doc = decl.Doc
+				}
+
+				docs[typeSpec.Name.Name] = docText(doc)
+
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				for _, field := range structType.Fields.List {
+					for _, fieldName := range field.Names {
+						docs[typeSpec.Name.Name+"."+fieldName.Name] = docText(field.Doc)
+					}
+				}
+			}
+
+			return false
+		})
+	}
+
+	return docs, nil
+}
+
+// docText joins the lines of a doc comment, code generator markers like +genclient are omitted.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+
+	var lines []string
+	for line := range strings.SplitSeq(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "+") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, " ")
+}
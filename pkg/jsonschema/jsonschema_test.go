@@ -0,0 +1,43 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v1"
+	"github.com/raffis/gitops-zombies/pkg/apis/gitopszombies/v2"
+)
+
+func TestSchemasInSync(t *testing.T) {
+	tests := []struct {
+		dir      string
+		gv       schema.GroupVersion
+		obj      runtime.Object
+		embedded []byte
+	}{
+		{
+			dir:      "../apis/gitopszombies/v1",
+			gv:       v1.SchemeGroupVersion,
+			obj:      &v1.Config{},
+			embedded: v1.JSONSchema,
+		},
+		{
+			dir:      "../apis/gitopszombies/v2",
+			gv:       v2.SchemeGroupVersion,
+			obj:      &v2.Config{},
+			embedded: v2.JSONSchema,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.gv.String(), func(t *testing.T) {
+			generated, err := Generate(test.dir, test.gv, test.obj)
+			require.NoError(t, err)
+			assert.Equal(t, string(test.embedded), string(generated), "schema is outdated, run make generate")
+		})
+	}
+}